@@ -0,0 +1,78 @@
+// Command rpca-eval scores anomaly detectors on a copy of the Numenta Anomaly
+// Benchmark, printing the precision, recall, F1 and NAB score of every series
+// and of the whole benchmark:
+//
+//	rpca-eval -nab ~/NAB -frequency 288 -detector ensemble
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/berkmancenter/rpca"
+	"github.com/berkmancenter/rpca/eval"
+)
+
+func main() {
+	root := flag.String("nab", "NAB", "directory holding the benchmark's data and labels")
+	frequency := flag.Int("frequency", 288, "points per period of the series")
+	detectorName := flag.String("detector", "rpca", "detector to score: rpca, esd or ensemble")
+	profileName := flag.String("profile", "standard", "NAB profile: standard, reward_low_fp or reward_low_fn")
+	maxAnomalies := flag.Float64("max-anomalies", 0.02, "largest fraction of anomalies the esd detector looks for")
+	flag.Parse()
+
+	profiles := map[string]eval.Profile{
+		"standard":      eval.Standard,
+		"reward_low_fp": eval.RewardLowFP,
+		"reward_low_fn": eval.RewardLowFN,
+	}
+	profile, ok := profiles[*profileName]
+	if !ok {
+		fail(fmt.Errorf("unknown profile %q", *profileName))
+	}
+	if *frequency <= 0 {
+		fail(fmt.Errorf("frequency must be positive"))
+	}
+	rpcaDetector := eval.Aligned(rpca.RPCA(rpca.Frequency(*frequency)), *frequency)
+	esdDetector := rpca.SeasonalESD(*maxAnomalies, rpca.Frequency(*frequency))
+	var detector rpca.Detector
+	switch *detectorName {
+	case "rpca":
+		detector = rpcaDetector
+	case "esd":
+		detector = esdDetector
+	case "ensemble":
+		detector = rpca.Ensemble{
+			Detectors:   []rpca.Detector{rpcaDetector, esdDetector},
+			Combination: rpca.MaxScore,
+		}
+	default:
+		fail(fmt.Errorf("unknown detector %q", *detectorName))
+	}
+
+	series, err := eval.LoadNAB(*root)
+	if err != nil {
+		fail(err)
+	}
+	report := eval.Evaluate(detector, series, profile)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "series\twindows\tTP\tFP\tFN\tprecision\trecall\tF1\tscore\t")
+	for _, r := range append(report.Results, report.Total) {
+		if r.Err != nil {
+			fmt.Fprintf(os.Stderr, "rpca-eval: %v: %v\n", r.Name, r.Err)
+			continue
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%.3f\t%.3f\t%.3f\t%.1f\t\n",
+			r.Name, r.Windows, r.TruePositives, r.FalsePositives,
+			r.FalseNegatives, r.Precision, r.Recall, r.F1, r.Score)
+	}
+	w.Flush()
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "rpca-eval:", err)
+	os.Exit(1)
+}
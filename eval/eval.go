@@ -0,0 +1,277 @@
+/*
+Package eval measures how well anomaly detectors find labeled anomalies, so
+that changes to options or detectors can be compared on real data. It loads
+labeled series, such as those of the Numenta Anomaly Benchmark, runs any
+rpca.Detector on them, and reports precision, recall, F1 and NAB-style scores:
+
+	series, err := eval.LoadNAB("NAB")
+	...
+	detector := eval.Aligned(rpca.RPCA(rpca.Frequency(288)), 288)
+	report := eval.Evaluate(detector, series, eval.Standard)
+	fmt.Println(report.Total.F1, report.Total.Score)
+
+The rpca-eval command does the same from the command line.
+*/
+package eval
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/berkmancenter/rpca"
+)
+
+// Series is a time series labeled with the windows in which it is anomalous.
+type Series struct {
+	Name       string
+	Timestamps []time.Time
+	Values     []float64
+	Windows    []Window
+}
+
+// Window is a labeled anomaly: the points from index Start up to but not
+// including index End.
+type Window struct {
+	Start, End int
+}
+
+// Profile weighs the outcomes of a NAB score.
+type Profile struct {
+	TruePositive, FalsePositive, FalseNegative float64
+}
+
+// The application profiles of NAB.
+var (
+	Standard    = Profile{TruePositive: 1, FalsePositive: 0.11, FalseNegative: 1}
+	RewardLowFP = Profile{TruePositive: 1, FalsePositive: 0.22, FalseNegative: 1}
+	RewardLowFN = Profile{TruePositive: 1, FalsePositive: 0.11, FalseNegative: 2}
+)
+
+/*
+Result is how a detector did on one series, or on all of them. Detection is
+scored by window: a window is a true positive if any point in it is flagged
+and a false negative otherwise, while every flagged point outside the windows
+is a false positive. Precision and recall are computed from those counts, and
+are zero when undefined.
+*/
+type Result struct {
+	Name    string
+	Windows int
+
+	TruePositives, FalsePositives, FalseNegatives int
+	Precision, Recall, F1                         float64
+
+	// RawScore is the NAB score: every window adds the true positive weight
+	// if its first flagged point is at its start, shrinking towards zero as
+	// the point nears its end, or subtracts the false negative weight if it
+	// has none. Every false positive subtracts up to the false positive
+	// weight, less if it closely follows a window.
+	RawScore float64
+
+	// Score is the raw score normalized so that flagging nothing scores 0 and
+	// flagging the start of every window scores 100. It is NaN for a series
+	// without windows.
+	Score float64
+
+	// Err is why the detector failed on the series, such as a length it
+	// rejects, or nil if it did not. A failed series has no counts or scores
+	// and is left out of the total.
+	Err error
+}
+
+// Report holds the result of every series, and their total.
+type Report struct {
+	Results []Result
+	Total   Result
+}
+
+// Evaluate runs the detector on every series and scores what it found. A
+// series the detector fails on, by panicking, is reported with the error in
+// its Result and the rest are still evaluated.
+func Evaluate(detector rpca.Detector, series []Series, profile Profile) Report {
+	report := Report{Total: Result{Name: "total"}}
+	for _, s := range series {
+		anomalies, err := detect(detector, s.Values)
+		if err != nil {
+			report.Results = append(report.Results,
+				Result{Name: s.Name, Windows: len(s.Windows), Err: err})
+			continue
+		}
+		result := score(s.Name, anomalies.Positions, s.Windows, profile)
+		report.Results = append(report.Results, result)
+		report.Total.Windows += result.Windows
+		report.Total.TruePositives += result.TruePositives
+		report.Total.FalsePositives += result.FalsePositives
+		report.Total.FalseNegatives += result.FalseNegatives
+		report.Total.RawScore += result.RawScore
+	}
+	report.Total.finish(profile)
+	return report
+}
+
+// detect runs the detector on a series, recovering from any panic as an error.
+func detect(detector rpca.Detector, values []float64) (anomalies rpca.Anomalies,
+	err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return detector.Detect(values), nil
+}
+
+// Score scores the flagged positions of one series against its windows.
+func Score(positions []bool, windows []Window, profile Profile) Result {
+	return score("", positions, windows, profile)
+}
+
+func score(name string, positions []bool, windows []Window, profile Profile) Result {
+	result := Result{Name: name, Windows: len(windows)}
+	detected := make([]bool, len(windows))
+	for i, flagged := range positions {
+		if !flagged {
+			continue
+		}
+		w, inside := windowOf(i, windows)
+		if inside {
+			if !detected[w] {
+				detected[w] = true
+				result.TruePositives++
+				result.RawScore += profile.TruePositive *
+					scaledSigmoid(relativePosition(i, windows[w]))
+			}
+			continue
+		}
+		result.FalsePositives++
+		if w < 0 {
+			result.RawScore -= profile.FalsePositive
+		} else {
+			result.RawScore += profile.FalsePositive *
+				scaledSigmoid(relativePosition(i, windows[w]))
+		}
+	}
+	for _, d := range detected {
+		if !d {
+			result.FalseNegatives++
+			result.RawScore -= profile.FalseNegative
+		}
+	}
+	result.finish(profile)
+	return result
+}
+
+// windowOf returns the window containing point i and true, or the last window
+// before it and false, or -1 and false if there is none.
+func windowOf(i int, windows []Window) (int, bool) {
+	before := -1
+	for w, window := range windows {
+		if i >= window.Start && i < window.End {
+			return w, true
+		}
+		if window.End <= i && (before < 0 || window.End > windows[before].End) {
+			before = w
+		}
+	}
+	return before, false
+}
+
+// relativePosition returns where point i is relative to the end of a window,
+// in window lengths: -1 at its start, 0 at its end, and positive after it.
+func relativePosition(i int, window Window) float64 {
+	return float64(i-window.End) / float64(max(window.End-window.Start, 1))
+}
+
+// scaledSigmoid is NAB's scoring curve, which is near 1 well before the end of
+// a window, 0 at its end, and near -1 well after it.
+func scaledSigmoid(y float64) float64 {
+	return 2/(1+math.Exp(5*y)) - 1
+}
+
+// finish computes the result's ratios and normalized score from its counts
+// and raw score.
+func (r *Result) finish(profile Profile) {
+	r.Precision = ratio(r.TruePositives, r.TruePositives+r.FalsePositives)
+	r.Recall = ratio(r.TruePositives, r.TruePositives+r.FalseNegatives)
+	if r.Precision+r.Recall > 0 {
+		r.F1 = 2 * r.Precision * r.Recall / (r.Precision + r.Recall)
+	}
+	null := -profile.FalseNegative * float64(r.Windows)
+	perfect := profile.TruePositive * scaledSigmoid(-1) * float64(r.Windows)
+	r.Score = math.NaN()
+	if r.Windows > 0 {
+		r.Score = 100 * (r.RawScore - null) / (perfect - null)
+	}
+}
+
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+/*
+Aligned wraps a detector that needs the length of the series to be divisible
+by the frequency, such as one made by rpca.RPCA, so that it can run on series
+of any length. The first len(series) % frequency points are dropped before
+detecting and are never flagged.
+*/
+func Aligned(detector rpca.Detector, frequency int) rpca.Detector {
+	return rpca.DetectorFunc(func(series []float64) rpca.Anomalies {
+		skip := len(series) % frequency
+		found := detector.Detect(series[skip:])
+		anomalies := rpca.Anomalies{
+			Positions:    make([]bool, len(series)),
+			Values:       make([]float64, len(series)),
+			NormedValues: make([]float64, len(series)),
+		}
+		copy(anomalies.Positions[skip:], found.Positions)
+		copy(anomalies.Values[skip:], found.Values)
+		copy(anomalies.NormedValues[skip:], found.NormedValues)
+		return anomalies
+	})
+}
+
+/*
+Objective returns an objective for rpca.GridSearch and rpca.RandomSearch that
+scores the anomalies found in the series, in order, by their total NAB score
+under the profile. The anomalies found in a series may be shorter than it, in
+which case they are taken to cover its last points, as with Values. Anomalies
+longer than their series, or for a different number of series, panic, which
+fails the trial:
+
+	trials := rpca.GridSearch(eval.Values(series, 288), space,
+		eval.Objective(series, eval.Standard))
+*/
+func Objective(series []Series, profile Profile) rpca.Objective {
+	return func(found []rpca.Anomalies) float64 {
+		if len(found) != len(series) {
+			panic("Anomalies and series of different lengths")
+		}
+		total := Result{}
+		for i, s := range series {
+			if len(found[i].Positions) > len(s.Values) {
+				panic("Anomalies longer than their series")
+			}
+			positions := make([]bool, len(s.Values))
+			copy(positions[len(positions)-len(found[i].Positions):], found[i].Positions)
+			result := score(s.Name, positions, s.Windows, profile)
+			total.Windows += result.Windows
+			total.RawScore += result.RawScore
+		}
+		total.finish(profile)
+		return total.Score
+	}
+}
+
+// Values returns the values of every series without their first
+// len(values) % frequency points, so that their lengths are divisible by the
+// frequency, as Aligned does.
+func Values(series []Series, frequency int) [][]float64 {
+	values := make([][]float64, len(series))
+	for i, s := range series {
+		values[i] = s.Values[len(s.Values)%frequency:]
+	}
+	return values
+}
@@ -0,0 +1,175 @@
+package eval
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/berkmancenter/rpca"
+)
+
+func TestLoadNAB(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"labels/combined_windows.json": `{
+			"realKnownCause/machine.csv": [
+				["2014-02-19 10:15:00.000000", "2014-02-19 10:25:00.000000"]
+			]
+		}`,
+		"data/realKnownCause/machine.csv": "timestamp,value\n" +
+			"2014-02-19 10:00:00,1\n2014-02-19 10:05:00,2\n2014-02-19 10:10:00,3\n" +
+			"2014-02-19 10:15:00,40\n2014-02-19 10:20:00,50\n2014-02-19 10:25:00,6\n" +
+			"2014-02-19 10:30:00,7\n",
+		"data/artificialNoAnomaly/flat.csv": "timestamp,value\n" +
+			"2014-04-01 00:00:00,5\n2014-04-01 00:05:00,5\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	series, err := LoadNAB(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 2 || series[0].Name != "artificialNoAnomaly/flat.csv" ||
+		series[1].Name != "realKnownCause/machine.csv" {
+		t.Fatalf("Expected both series, sorted by name, but got %v", series)
+	}
+	if len(series[0].Values) != 2 || len(series[0].Windows) != 0 {
+		t.Errorf("Expected two unlabeled points but got %v", series[0])
+	}
+	machine := series[1]
+	if len(machine.Values) != 7 || machine.Values[4] != 50 {
+		t.Errorf("Expected seven points but got %v", machine.Values)
+	}
+	if len(machine.Windows) != 1 || machine.Windows[0] != (Window{Start: 3, End: 6}) {
+		t.Errorf("Expected the window to cover points 3 to 5 but got %v",
+			machine.Windows)
+	}
+}
+
+func TestScore(t *testing.T) {
+	windows := []Window{{Start: 10, End: 20}, {Start: 40, End: 50}}
+	flag := func(points ...int) []bool {
+		positions := make([]bool, 60)
+		for _, p := range points {
+			positions[p] = true
+		}
+		return positions
+	}
+
+	// A detection at the start of the first window, another later in it, one
+	// a window length after it, one before any window, and none in the second.
+	result := Score(flag(5, 10, 15, 30), windows, Standard)
+	if result.TruePositives != 1 || result.FalsePositives != 2 ||
+		result.FalseNegatives != 1 {
+		t.Errorf("Expected 1 true positive, 2 false positives and 1 false "+
+			"negative but got %+v", result)
+	}
+	if math.Abs(result.Precision-1.0/3) > 1e-9 || math.Abs(result.Recall-0.5) > 1e-9 ||
+		math.Abs(result.F1-0.4) > 1e-9 {
+		t.Errorf("Expected a precision of 1/3, a recall of 1/2 and an F1 of 0.4 "+
+			"but got %+v", result)
+	}
+	raw := scaledSigmoid(-1) + 0.11*scaledSigmoid(1) - 0.11 - 1
+	if math.Abs(result.RawScore-raw) > 1e-9 {
+		t.Errorf("Expected a raw score of %v but got %v", raw, result.RawScore)
+	}
+
+	tests := []struct {
+		description string
+		positions   []bool
+		score       float64
+	}{
+		{"perfect", flag(10, 40), 100},
+		{"nothing", flag(), 0},
+	}
+	for _, test := range tests {
+		result := Score(test.positions, windows, Standard)
+		if math.Abs(result.Score-test.score) > 1e-9 {
+			t.Errorf("Failed '%v'. Expected a score of %v but got %v",
+				test.description, test.score, result.Score)
+		}
+	}
+	if late, early := Score(flag(18), windows, Standard),
+		Score(flag(11), windows, Standard); late.Score >= early.Score {
+		t.Errorf("Expected an early detection to score more than a late one "+
+			"but got %v and %v", early.Score, late.Score)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	series := make([]float64, 10*8+3)
+	for i := range series {
+		series[i] = math.Sin(2 * math.Pi * float64(i) / 8)
+	}
+	series[50] += 10
+	labeled := []Series{{Name: "spike", Values: series, Windows: []Window{{48, 53}}}}
+	detector := Aligned(rpca.RPCA(rpca.Frequency(8), rpca.AutoDiff(false)), 8)
+
+	report := Evaluate(detector, labeled, Standard)
+	if len(report.Results) != 1 || report.Total.TruePositives != 1 ||
+		report.Total.FalseNegatives != 0 {
+		t.Errorf("Expected the spike to be found but got %+v", report)
+	}
+	if report.Total.Score <= 0 {
+		t.Errorf("Expected a positive score but got %v", report.Total.Score)
+	}
+}
+
+func TestObjective(t *testing.T) {
+	series := []Series{{Values: make([]float64, 19), Windows: []Window{{Start: 10, End: 15}}}}
+	values := Values(series, 8)
+	if len(values[0]) != 16 {
+		t.Fatalf("Expected 16 values but got %v", len(values[0]))
+	}
+	found := rpca.Anomalies{Positions: make([]bool, 16)}
+	found.Positions[10-3] = true
+	score := Objective(series, Standard)([]rpca.Anomalies{found})
+	if math.Abs(score-100) > 1e-9 {
+		t.Errorf("Expected a detection at the start of the window to score 100 "+
+			"but got %v", score)
+	}
+}
+
+func TestEvaluateFailedSeries(t *testing.T) {
+	series := make([]float64, 10*8)
+	for i := range series {
+		series[i] = math.Sin(2 * math.Pi * float64(i) / 8)
+	}
+	series[50] += 10
+	labeled := []Series{
+		{Name: "ragged", Values: series[:77], Windows: []Window{{48, 53}}},
+		{Name: "spike", Values: series, Windows: []Window{{48, 53}}},
+	}
+	// Without Aligned, the ragged series is not divisible by the frequency.
+	report := Evaluate(rpca.RPCA(rpca.Frequency(8), rpca.AutoDiff(false)), labeled,
+		Standard)
+	if len(report.Results) != 2 || report.Results[0].Err == nil ||
+		report.Results[1].Err != nil {
+		t.Fatalf("Expected only the ragged series to fail but got %+v",
+			report.Results)
+	}
+	if report.Total.Windows != 1 || report.Total.TruePositives != 1 {
+		t.Errorf("Expected the total to hold only the spike but got %+v",
+			report.Total)
+	}
+}
+
+func TestObjectiveRejectsLongAnomalies(t *testing.T) {
+	series := []Series{{Values: make([]float64, 8)}}
+	found := []rpca.Anomalies{{Positions: make([]bool, 16)}}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected anomalies longer than their series to panic")
+		}
+	}()
+	Objective(series, Standard)(found)
+}
@@ -0,0 +1,157 @@
+package eval
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The layouts of timestamps in NAB data and label files.
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.000000",
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+}
+
+func parseTime(text string) (time.Time, error) {
+	var err error
+	for _, layout := range timeLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, strings.TrimSpace(text)); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+/*
+LoadNAB loads every labeled series of a copy of the Numenta Anomaly Benchmark
+(https://github.com/numenta/NAB) at root. Series are read from the CSV files
+under root/data, and their anomaly windows from root/labels/combined_windows.json,
+which maps the path of each file relative to root/data to a list of windows,
+each a pair of start and end timestamps. Series are named by that relative
+path, use forward slashes, and are sorted by name. Files without labels are
+loaded with no windows.
+*/
+func LoadNAB(root string) ([]Series, error) {
+	file, err := os.Open(filepath.Join(root, "labels", "combined_windows.json"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var labels map[string][][]string
+	if err := json.NewDecoder(file).Decode(&labels); err != nil {
+		return nil, fmt.Errorf("combined_windows.json: %v", err)
+	}
+
+	dataDir := filepath.Join(root, "data")
+	var series []Series
+	err = filepath.Walk(dataDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != ".csv" {
+			return err
+		}
+		rel, err := filepath.Rel(dataDir, path)
+		if err != nil {
+			return err
+		}
+		s, err := LoadCSV(path)
+		if err != nil {
+			return err
+		}
+		s.Name = filepath.ToSlash(rel)
+		for _, window := range labels[s.Name] {
+			if len(window) != 2 {
+				return fmt.Errorf("%v: window without a start and end", s.Name)
+			}
+			start, err := parseTime(window[0])
+			if err != nil {
+				return fmt.Errorf("%v: %v", s.Name, err)
+			}
+			end, err := parseTime(window[1])
+			if err != nil {
+				return fmt.Errorf("%v: %v", s.Name, err)
+			}
+			s.Windows = append(s.Windows, s.window(start, end))
+		}
+		series = append(series, s)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(series, func(a, b int) bool { return series[a].Name < series[b].Name })
+	return series, nil
+}
+
+// LoadCSV loads a series from a CSV file with a header row and columns named
+// timestamp and value, as in NAB. The series is named after the file and has
+// no windows.
+func LoadCSV(path string) (Series, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Series{}, err
+	}
+	defer file.Close()
+	s, err := ReadCSV(file)
+	if err != nil {
+		return Series{}, fmt.Errorf("%v: %v", path, err)
+	}
+	s.Name = filepath.Base(path)
+	return s, nil
+}
+
+// ReadCSV reads a series in the format described by LoadCSV.
+func ReadCSV(r io.Reader) (Series, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return Series{}, err
+	}
+	if len(records) == 0 {
+		return Series{}, fmt.Errorf("no header row")
+	}
+	timeColumn, valueColumn := -1, -1
+	for i, name := range records[0] {
+		switch strings.TrimSpace(name) {
+		case "timestamp":
+			timeColumn = i
+		case "value":
+			valueColumn = i
+		}
+	}
+	if timeColumn < 0 || valueColumn < 0 {
+		return Series{}, fmt.Errorf("no timestamp and value columns")
+	}
+	var s Series
+	for line, record := range records[1:] {
+		t, err := parseTime(record[timeColumn])
+		if err != nil {
+			return Series{}, fmt.Errorf("line %v: %v", line+2, err)
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(record[valueColumn]), 64)
+		if err != nil {
+			return Series{}, fmt.Errorf("line %v: %v", line+2, err)
+		}
+		s.Timestamps = append(s.Timestamps, t)
+		s.Values = append(s.Values, v)
+	}
+	return s, nil
+}
+
+// window returns the window of points with timestamps from start to end,
+// inclusive.
+func (s Series) window(start, end time.Time) Window {
+	first := sort.Search(len(s.Timestamps), func(i int) bool {
+		return !s.Timestamps[i].Before(start)
+	})
+	last := sort.Search(len(s.Timestamps), func(i int) bool {
+		return s.Timestamps[i].After(end)
+	})
+	return Window{Start: first, End: last}
+}
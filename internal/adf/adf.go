@@ -0,0 +1,116 @@
+/*
+Package adf runs the Augmented Dickey-Fuller test that rpca uses to decide
+whether a time series must be differenced before it is decomposed. It follows
+the AugmentedDickeyFuller class of Netflix's Surus, whose decompositions the
+rpca tests reproduce.
+
+The changes of the series are regressed on the previous level, a constant, a
+linear trend and lagged changes:
+
+	y[t] - y[t-1] = beta*y[t-1] + alpha + delta*t
+		+ gamma[1]*(y[t-1] - y[t-2]) + ... + e[t]
+
+The test statistic is the t statistic of beta. As in Surus, a series needs
+differencing when the statistic is at or below the critical value, and is
+treated as stationary otherwise.
+*/
+package adf
+
+import (
+	"math"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// DefaultCriticalValue is the critical value used when none is given.
+const DefaultCriticalValue = -3.45
+
+// ADF is an Augmented Dickey-Fuller test of one series.
+type ADF struct {
+	Series []float64
+
+	// The statistic at or below which the series needs differencing.
+	CriticalValue float64
+
+	// The number of lagged changes in the regression.
+	Lag int
+
+	// The t statistic of the previous level, set by Run.
+	Statistic float64
+}
+
+// New returns a test of a copy of the series. A critical value of zero
+// selects DefaultCriticalValue, and a negative lag selects the cube root of
+// one less than the length of the series, rounded down.
+func New(series []float64, criticalValue float64, lag int) *ADF {
+	if criticalValue == 0 {
+		criticalValue = DefaultCriticalValue
+	}
+	if lag < 0 {
+		lag = int(math.Floor(math.Cbrt(float64(len(series) - 1))))
+	}
+	return &ADF{
+		Series:        append([]float64(nil), series...),
+		CriticalValue: criticalValue,
+		Lag:           lag,
+	}
+}
+
+// Run computes the test statistic. Series too short to fit the regression, or
+// that it fits exactly, get a statistic of NaN, which is treated as stationary
+// so that they are left as they are.
+func (a *ADF) Run() {
+	a.Statistic = math.NaN()
+	if len(a.Series) < 2 {
+		return
+	}
+	changes := make([]float64, len(a.Series)-1)
+	for t := range changes {
+		changes[t] = a.Series[t+1] - a.Series[t]
+	}
+
+	// Row r regresses change t on the level before it, a constant, the trend
+	// and the lag changes before it.
+	cols := a.Lag + 3
+	rows := len(changes) - a.Lag
+	if rows <= cols {
+		return
+	}
+	design := mat64.NewDense(rows, cols, nil)
+	response := mat64.NewDense(rows, 1, nil)
+	for r := 0; r < rows; r++ {
+		t := r + a.Lag
+		response.Set(r, 0, changes[t])
+		design.Set(r, 0, a.Series[t])
+		design.Set(r, 1, 1)
+		design.Set(r, 2, float64(t+1))
+		for j := 1; j <= a.Lag; j++ {
+			design.Set(r, 2+j, changes[t-j])
+		}
+	}
+
+	var gram, inverse, projection, beta, fitted mat64.Dense
+	gram.Mul(design.T(), design)
+	if err := inverse.Inverse(&gram); err != nil {
+		return
+	}
+	projection.Mul(&inverse, design.T())
+	beta.Mul(&projection, response)
+	fitted.Mul(design, &beta)
+	residuals := 0.0
+	for r := 0; r < rows; r++ {
+		d := response.At(r, 0) - fitted.At(r, 0)
+		residuals += d * d
+	}
+	variance := residuals / float64(rows-cols)
+	if variance == 0 {
+		return
+	}
+	a.Statistic = beta.At(0, 0) / math.Sqrt(variance*inverse.At(0, 0))
+}
+
+// IsStationary returns whether the series can be decomposed without
+// differencing.
+func (a *ADF) IsStationary() bool {
+	return !(a.Statistic <= a.CriticalValue)
+}
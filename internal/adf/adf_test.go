@@ -0,0 +1,41 @@
+package adf
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestStatistic(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	noise := make([]float64, 200)
+	walk := make([]float64, len(noise))
+	for i := range noise {
+		noise[i] = r.NormFloat64()
+		if i > 0 {
+			walk[i] = walk[i-1] + noise[i]
+		}
+	}
+	if test := New(noise, 0, -1); test.Lag != 5 {
+		t.Errorf("Expected a default lag of 5 but got %v", test.Lag)
+	}
+
+	// White noise rejects the unit root by far, a random walk does not.
+	a, b := New(noise, 0, -1), New(walk, 0, -1)
+	a.Run()
+	b.Run()
+	if a.Statistic > -5 || b.Statistic < DefaultCriticalValue {
+		t.Errorf("Expected a statistic below -5 for noise and above %v for a "+
+			"walk, but got %v and %v", DefaultCriticalValue, a.Statistic,
+			b.Statistic)
+	}
+}
+
+func TestShortSeries(t *testing.T) {
+	test := New([]float64{1, 2, 3}, 0, -1)
+	test.Run()
+	if !math.IsNaN(test.Statistic) || !test.IsStationary() {
+		t.Errorf("Expected a NaN statistic treated as stationary, but got %v",
+			test.Statistic)
+	}
+}
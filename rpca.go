@@ -9,12 +9,12 @@ implementations written in R, C++, Java, and Javascript.
 package rpca
 
 import (
-	"github.com/berkmancenter/adf"
+	"github.com/berkmancenter/rpca/internal/adf"
 	"github.com/gonum/matrix"
 	"github.com/gonum/matrix/mat64"
 	"github.com/gonum/stat"
 
-	"fmt"
+	"log/slog"
 	"math"
 )
 
@@ -37,8 +37,47 @@ type Anomalies struct {
 	// before detecting anomalies. The anomalousness of each point is computed in
 	// this Z-scored space before being transformed back into the domain of the
 	// given time series. Sometimes, it's useful to have the normalized values,
-	// for example, when comparing anomalies across time series.
+	// for example, when comparing anomalies across time series. With the Counts
+	// option, it is the transformed counts that are Z scored.
 	NormedValues []float64
+
+	// PValues holds, for every point in the provided time series, the
+	// probability of seeing a deviation from the low-rank fit at least as large
+	// if the point were only noise, taking the noise to be normal with the
+	// spread of the noise component. The values are Benjamini-Hochberg
+	// adjusted across the series, so as long as the noise is close to normal,
+	// flagging every point with a p-value below q keeps the expected false
+	// discovery rate near q. Excluded and other masked points have a p-value
+	// of one. Only populated when the PValues option is set.
+	PValues []float64
+
+	// A slice of booleans indicating which whole periods of the provided time
+	// series were anomalous. The first period is made of the first frequency
+	// points, or fewer with the PhaseOffset or SeasonStart options, or the
+	// first calendar month with the Monthly option, and so on. Only populated
+	// when the OutlierPursuit option is set.
+	Periods []bool
+
+	// LevelShifts lists the points where the provided time series settled at a
+	// new level, as opposed to briefly spiking or dipping. Only populated when
+	// the LevelShifts option is set.
+	LevelShifts []LevelShift
+
+	// A slice of booleans indicating which values in the provided time series
+	// were excluded from detection with the Exclude or ExcludeTimes options, or
+	// masked as holidays with the Holidays option.
+	// Excluded points are never anomalous.
+	Excluded []bool
+
+	// ExpectedDeviations holds how far each excluded point was from the
+	// low-rank fit, in the same units as Values, so planned events can be
+	// reported without being flagged. Points that were not excluded have a
+	// value of zero.
+	ExpectedDeviations []float64
+
+	// Diagnostics records how the algorithm converged. Only populated when the
+	// CollectDiagnostics option is set.
+	Diagnostics *Diagnostics
 }
 
 /*
@@ -53,57 +92,194 @@ options like so:
 	anoms := rpca.FindAnomalies(series, rpca.Frequency(7), rpca.AutoDiff(true))
 
 The interface is designed to match that of Netflix's anomaly detection
-R package.
+R package. RPCA wraps FindAnomalies and its options in a Detector, so it can be
+combined with other detectors in an Ensemble.
 */
 func FindAnomalies(series []float64, options ...func(*rpcaConfig) error) Anomalies {
+	conf := newConfig(series, options)
+	return findAnomalies(series, &conf)
+}
+
+func findAnomalies(series []float64, conf *rpcaConfig) Anomalies {
+	var diagnostics *Diagnostics
+	if conf.diagnostics {
+		diagnostics = conf.record()
+	}
+	transformed := conf.transform(series)
+	decomposed := decompose(transformed, conf)
+	anomalies := decomposedToAnomalies(&decomposed)
+	if conf.counts {
+		anomalies.Values = countDeviations(series, transformed, anomalies.Values)
+	}
+	if conf.pValues {
+		anomalies.PValues = computePValues(&decomposed, conf)
+	}
+	if conf.outlierPursuit {
+		anomalies.Periods = decomposed.fold.periods(anomalies.Positions)
+	}
+	if conf.levelShifts {
+		anomalies.LevelShifts = findLevelShifts(series, anomalies.Values,
+			decomposed.differenced, decomposed.fold.period())
+	}
+	if excluded := conf.excluded(len(series)); excluded != nil {
+		anomalies.Excluded = excluded
+		anomalies.ExpectedDeviations = expectedDeviations(&decomposed, excluded)
+		if conf.counts {
+			anomalies.ExpectedDeviations = countDeviations(series, transformed,
+				anomalies.ExpectedDeviations)
+		}
+	}
+	if diagnostics != nil {
+		diagnostics.Converged = decomposed.converged
+		anomalies.Diagnostics = diagnostics
+	}
+	return anomalies
+}
+
+// configure applies the options over the defaults, leaving the S penalty NaN
+// unless an option set it. It panics with the error of any option that rejects
+// its arguments.
+func configure(options []func(*rpcaConfig) error) rpcaConfig {
 	conf := rpcaConfig{
-		frequency: 7,
-		autodiff:  true,
-		forcediff: false,
-		scale:     true,
-		lPenalty:  1.0,
-		sPenalty:  1.4,
-		verbose:   false,
+		frequency:  7,
+		autodiff:   true,
+		forcediff:  false,
+		scale:      true,
+		lPenalty:   1.0,
+		sPenalty:   math.NaN(),
+		verbose:    false,
+		confidence: 0.95,
+		noise:      Gaussian(),
+		rank:       1,
+		sparsity:   0.05,
 	}
 
-	// Apply because we need to know the frequency
 	for _, option := range options {
-		option(&conf)
+		if err := option(&conf); err != nil {
+			panic(err)
+		}
 	}
+	if conf.solver != Surus {
+		conf.requireSurus()
+	}
+	return conf
+}
 
-	floatFreq := float64(conf.frequency)
-	conf.sPenalty = 1.4 / math.Sqrt(math.Max(floatFreq, float64(len(series))/floatFreq))
-
-	// Apply again in case user provided S penalty
-	for _, option := range options {
-		option(&conf)
+// requireSurus panics if any option needs what only the Surus solver does:
+// weighting or masking points in the fit, a robust noise model, or starting
+// from a previous run. The other solvers would fit a different model.
+func (conf *rpcaConfig) requireSurus() {
+	switch {
+	case conf.state != nil:
+		panic("Warm start requires the Surus solver")
+	case conf.weights != nil:
+		panic("Weights require the Surus solver")
+	case len(conf.exclusions) > 0:
+		panic("Exclusions require the Surus solver")
+	case conf.calendar != nil:
+		panic("Holidays require the Surus solver")
+	case conf.monthly:
+		panic("Monthly folding requires the Surus solver")
+	case conf.noise != Gaussian():
+		panic("Huber noise requires the Surus solver")
 	}
+}
 
-	mat := buildMatrix(series, conf.frequency)
-	decomposed := computeRPCA(mat, &conf)
-	return decomposedToAnomalies(&decomposed)
+// newConfig builds the configuration for the given series, filling in defaults
+// that depend on the length of the series.
+func newConfig(series []float64, options []func(*rpcaConfig) error) rpcaConfig {
+	conf := configure(options)
+
+	// The default S penalty depends on the frequency, so fill it in unless the
+	// user provided one.
+	if math.IsNaN(conf.sPenalty) {
+		floatFreq := float64(conf.frequency)
+		if conf.monthly {
+			floatFreq = 31
+		}
+		conf.sPenalty = 1.4 / math.Sqrt(math.Max(floatFreq, float64(len(series))/floatFreq))
+	}
+	return conf
 }
 
 func decomposedToAnomalies(decomp *decomposedMatrix) Anomalies {
-	anomalyMat := mat64.DenseCopyOf(decomp.S.T())
-	anomalies := anomalyMat.RawMatrix().Data
-	normedAnomalyMat := mat64.DenseCopyOf(decomp.SNormed.T())
-	normedAnomalies := normedAnomalyMat.RawMatrix().Data
+	anomalies := decomp.unfold(decomp.S)
+	normedAnomalies := decomp.unfold(decomp.SNormed)
 	positions := make([]bool, len(anomalies))
 	for i, v := range anomalies {
 		positions[i] = v != 0
 	}
-	return Anomalies{positions, anomalies, normedAnomalies}
+	return Anomalies{
+		Positions:    positions,
+		Values:       anomalies,
+		NormedValues: normedAnomalies,
+	}
 }
 
 type decomposedMatrix struct {
 	L, S, SNormed, E rPCAable
+	ENormed          rPCAable
 	converged        bool
 	iterations       int
+	differenced      bool
+	fold             *folding
+
+	// The weight of every cell, or nil if all cells are weighted equally.
+	weights *mat64.Dense
+}
+
+// unfold returns the values of one of the decomposed matrices in the order of
+// the time series it was built from.
+func (d *decomposedMatrix) unfold(mat rPCAable) []float64 {
+	return d.fold.unfold(d.cells(mat))
+}
+
+// weighted returns which points of the series count towards the fit, which
+// excludes masked points, or nil if all of them do.
+func (d *decomposedMatrix) weighted() []bool {
+	if d.weights == nil {
+		return nil
+	}
+	weighted := make([]bool, len(d.fold.cells))
+	for i, w := range d.fold.unfold(matrixData(d.weights)) {
+		weighted[i] = w != 0
+	}
+	return weighted
+}
+
+// weightedPoints returns the values of the points that count towards the fit.
+// The noise of the other points holds their whole deviation from it.
+func (d *decomposedMatrix) weightedPoints(values []float64) []float64 {
+	weighted := d.weighted()
+	if weighted == nil {
+		return values
+	}
+	var kept []float64
+	for i, v := range values {
+		if weighted[i] {
+			kept = append(kept, v)
+		}
+	}
+	return kept
 }
+
+// cells returns the cells of one of the decomposed matrices, counting down each
+// column of the folding in turn. Matrices of differenced series already have
+// their cells laid out column by column (see the end of computeFoldedRPCA).
+func (d *decomposedMatrix) cells(mat rPCAable) []float64 {
+	if d.differenced {
+		return append([]float64(nil), mat.RawMatrix().Data...)
+	}
+	return matrixData(mat)
+}
+
 type rPCAComponent struct {
 	matrix *mat64.Dense
 	norm   float64
+
+	// The number of singular values that survived thresholding. Only set for
+	// the low-rank component.
+	rank int
 }
 type rPCAable interface {
 	mat64.Mutable
@@ -111,10 +287,24 @@ type rPCAable interface {
 	Scale(f float64, a mat64.Matrix)
 }
 
+// decompose folds the series as configured and decomposes it.
+func decompose(series []float64, conf *rpcaConfig) decomposedMatrix {
+	fold := conf.folding(len(series))
+	return computeFoldedRPCA(fold.fold(series), fold, conf)
+}
+
+// computeRPCA decomposes a matrix that was folded regularly, one period per
+// column.
 func computeRPCA(mat rPCAable, conf *rpcaConfig) decomposedMatrix {
-	var mean, stdDev float64
+	rows, cols := mat.Dims()
+	return computeFoldedRPCA(mat, regularFolding(rows*cols, rows, 0), conf)
+}
+
+func computeFoldedRPCA(mat rPCAable, fold *folding, conf *rpcaConfig) decomposedMatrix {
+	mean, stdDev := 0.0, 1.0
 	rows, cols := mat.Dims()
 	needsDiff := false
+	logger := conf.log()
 	adf := adf.New(mat.RawMatrix().Data, 0, -1)
 
 	if conf.autodiff {
@@ -122,26 +312,99 @@ func computeRPCA(mat rPCAable, conf *rpcaConfig) decomposedMatrix {
 		needsDiff = !adf.IsStationary()
 	}
 
-	if needsDiff || conf.forcediff {
-		diffed := diff(matrixData(mat))
+	differenced := needsDiff || conf.forcediff
+	if differenced {
+		diffed := diff(fold.unfold(matrixData(mat)))
 		diffed = append([]float64{0}, diffed...)
-		mat = buildMatrix(diffed, conf.frequency)
+		mat = fold.fold(diffed)
+	}
+
+	w := conf.pointWeights(len(fold.cells))
+	if w != nil && differenced {
+		w = diffWeights(w)
+	}
+	weights := fold.foldWeights(w)
+	if weights != nil {
+		w = weights.RawMatrix().Data
 	}
 
 	if conf.scale {
-		mean, stdDev = stat.MeanStdDev(mat.RawMatrix().Data, nil)
-		if conf.verbose {
-			println("Mean, StdDev: ", mean, stdDev)
+		mean, stdDev = stat.MeanStdDev(mat.RawMatrix().Data, w)
+		if logger != nil {
+			logger.Debug("rpca: scaling", "mean", mean, "stdDev", stdDev)
 		}
 		add(mat, -mean)
 		mat.Scale(1.0/stdDev, mat)
 	}
 
+	var warm *start
+	if conf.state != nil {
+		warm = conf.warmStart(fold, differenced, mean, stdDev)
+	}
+
+	var sol solution
+	switch conf.solver {
+	case InexactALM:
+		sol = solveALM(mat, conf, logger)
+	case AltProj:
+		sol = solveAltProj(mat, conf, logger)
+	case GoDec:
+		sol = solveGoDec(mat, conf, logger)
+	default:
+		sol = solveSurus(mat, weights, warm, conf, logger)
+	}
+	if conf.state != nil {
+		conf.saveState(fold, differenced, sol, weights, mean, stdDev)
+	}
+	l, s, e := sol.l, sol.s, sol.e
+	if logger != nil {
+		logger.Debug("rpca: finished",
+			"converged", sol.converged, "iterations", sol.iterations)
+	}
+
+	sNormed := mat64.DenseCopyOf(s)
+	eNormed := mat64.DenseCopyOf(e)
+	if conf.scale {
+		l.Scale(stdDev, l)
+		add(l, mean)
+		s.Scale(stdDev, s)
+		e.Scale(stdDev, e)
+	}
+	// Not sure why this is required, but it is.
+	if differenced {
+		l = mat64.NewDense(rows, cols, matrixData(l))
+		s = mat64.NewDense(rows, cols, matrixData(s))
+		sNormed = mat64.NewDense(rows, cols, matrixData(sNormed))
+		e = mat64.NewDense(rows, cols, matrixData(e))
+		eNormed = mat64.NewDense(rows, cols, matrixData(eNormed))
+	}
+	return decomposedMatrix{l, s, sNormed, e, eNormed, sol.converged,
+		sol.iterations, differenced, fold, weights}
+}
+
+// solution is what a solver found for a prepared (differenced and scaled)
+// matrix.
+type solution struct {
+	l, s, e    *mat64.Dense
+	converged  bool
+	iterations int
+}
+
+// solveSurus runs the alternating soft-thresholding algorithm used by Netflix's
+// Surus project. If weights is not nil, it holds the weight of every cell. If
+// warm is not nil, the solver starts from it instead of from zero.
+func solveSurus(mat rPCAable, weights *mat64.Dense, warm *start,
+	conf *rpcaConfig, logger *slog.Logger) solution {
+	rows, cols := mat.Dims()
+
 	// Get initial mu, which is our convergence rate
 	mu := float64(cols*rows) / (4.0 * l1Norm(mat))
 	l := mat64.NewDense(rows, cols, nil)
 	s := mat64.NewDense(rows, cols, nil)
 	e := mat64.NewDense(rows, cols, nil)
+	if warm != nil {
+		l, mu = warm.l, warm.mu
+	}
 
 	// Initialize objective
 	previousObjective := 0.5 * math.Pow(mat64.Norm(mat, 2), 2)
@@ -150,14 +413,16 @@ func computeRPCA(mat rPCAable, conf *rpcaConfig) decomposedMatrix {
 	total := 1e-8 * previousObjective
 	difference := 2 * total
 
-	if conf.verbose {
-		println("Rows x cols:", rows, cols)
-		println("Objective initial:", objective)
-		println("Total initial:", total)
-		println("Diff initial:", difference)
-		println("Mu initial:", mu)
-		println("l1norm:", l1Norm(mat))
-		fmt.Printf("Matrix:\n%v\n\n", mat64.Formatted(mat))
+	if logger != nil {
+		logger.Debug("rpca: initial state",
+			"rows", rows,
+			"cols", cols,
+			"objective", objective,
+			"total", total,
+			"difference", difference,
+			"mu", mu,
+			"l1Norm", l1Norm(mat),
+			"matrix", mat64.Formatted(mat))
 	}
 
 	iter := 0
@@ -167,68 +432,88 @@ func computeRPCA(mat rPCAable, conf *rpcaConfig) decomposedMatrix {
 		thisLPenalty := mu * conf.lPenalty
 		thisSPenalty := mu * conf.sPenalty
 
-		if conf.verbose {
-			println("S penalty (", conf.sPenalty, ") with mu ", mu, ":", thisSPenalty)
-			println("L penalty with mu ", mu, ":", thisLPenalty)
-		}
+		delta := conf.noise.scaledDelta(mu, rows, cols)
 
-		sComp := computeS(mat, l, thisSPenalty)
+		sComp := computeS(mat, l, thisSPenalty, conf.outlierPursuit, weights)
 		s = sComp.matrix
-		lComp := computeL(mat, s, thisLPenalty)
+		var lComp rPCAComponent
+		if math.IsInf(delta, 1) && weights == nil {
+			lComp = computeL(mat, s, thisLPenalty)
+		} else {
+			lComp = computeL(mat, lOffset(mat, l, s, delta, weights), thisLPenalty)
+		}
 		l = lComp.matrix
-		eComp := computeE(mat, l, s)
+		eComp := computeE(mat, l, s, delta, weights)
 		e = eComp.matrix
 
 		objective = computeObjective(lComp.norm, sComp.norm, eComp.norm)
 		difference = math.Abs(previousObjective - objective)
 		previousObjective = objective
 
-		mu = computeDynamicMu(e)
-		if conf.verbose {
-			println("L S E norms:", lComp.norm, sComp.norm, eComp.norm)
-			println("Objective function: ", previousObjective, " on previous iteration ", iter)
-			println("Objective function: ", objective, " on iteration ", iter-1)
-			println("Mu after iteration ", iter, ": ", mu, "\n")
+		stats := IterationStats{
+			Iteration:  iter,
+			Mu:         mu,
+			LPenalty:   thisLPenalty,
+			SPenalty:   thisSPenalty,
+			LNorm:      lComp.norm,
+			SNorm:      sComp.norm,
+			ENorm:      eComp.norm,
+			Objective:  objective,
+			Difference: difference,
+			Rank:       lComp.rank,
+			Sparsity:   sparsity(s),
 		}
+		conf.report(stats, logger)
+
+		mu = computeDynamicMu(e, weights)
 		iter++
 	}
 	if iter < MAX_ITERS {
 		converged = true
 	}
-	sNormed := mat64.DenseCopyOf(s)
-	if conf.scale {
-		if conf.verbose {
-			println("Mean, StdDev: ", mean, stdDev)
-		}
-		l.Scale(stdDev, l)
-		add(l, mean)
-		s.Scale(stdDev, s)
-		e.Scale(stdDev, e)
-	}
-	// Not sure why this is required, but it is.
-	if needsDiff || conf.forcediff {
-		l = mat64.NewDense(rows, cols, matrixData(l))
-		s = mat64.NewDense(rows, cols, matrixData(s))
-		sNormed = mat64.NewDense(rows, cols, matrixData(sNormed))
-		e = mat64.NewDense(rows, cols, matrixData(e))
-	}
-	return decomposedMatrix{l, s, sNormed, e, converged, iter}
+	return solution{l, s, e, converged, iter}
+}
+
+// noiseScale inverts computeDynamicMu, estimating the standard deviation of
+// the noise from mu.
+func noiseScale(mu float64, r, c int) float64 {
+	return mu / math.Sqrt(2*math.Max(float64(r), float64(c)))
 }
 
-func computeDynamicMu(e *mat64.Dense) float64 {
+func computeDynamicMu(e, weights *mat64.Dense) float64 {
 	r, c := e.Dims()
-	eStdDev := stat.StdDev(e.RawMatrix().Data, nil)
+	var w []float64
+	if weights != nil {
+		w = weights.RawMatrix().Data
+	}
+	eStdDev := stat.StdDev(e.RawMatrix().Data, w)
 	mu := eStdDev * math.Sqrt(2*math.Max(float64(r), float64(c)))
 	return math.Max(0.01, mu)
 }
 
-func computeS(mat, l mat64.Matrix, penalty float64) rPCAComponent {
+// computeS thresholds the residual M - L. If weights is not nil, the data fit
+// of every cell is scaled by its weight, which divides its threshold by the
+// weight.
+func computeS(mat, l mat64.Matrix, penalty float64, columnwise bool,
+	weights *mat64.Dense) rPCAComponent {
 	r, c := mat.Dims()
 	residual := mat64.NewDense(r, c, nil)
 	residual.Sub(mat, l)
-	s := softThresholdMat(residual, penalty)
+	if columnwise {
+		// Scale the penalty so it applies to a whole column, whose norm grows
+		// with the square root of its length.
+		penalty *= math.Sqrt(float64(r))
+		s := softThresholdCols(residual, penalty, weights)
+		return rPCAComponent{matrix: s, norm: l21Norm(s) * penalty}
+	}
+	var s rPCAable
+	if weights != nil {
+		s = softThresholdWeighted(residual, penalty, weights)
+	} else {
+		s = softThresholdMat(residual, penalty)
+	}
 	norm := l1Norm(s) * penalty
-	return rPCAComponent{s.(*mat64.Dense), norm}
+	return rPCAComponent{matrix: s.(*mat64.Dense), norm: norm}
 }
 
 func computeObjective(lNorm, sNorm, eNorm float64) float64 {
@@ -253,18 +538,34 @@ func computeL(mat, s mat64.Matrix, penalty float64) rPCAComponent {
 	vT := v.T()
 	l.Mul(u, penalizedDiag)
 	l.Mul(l, vT)
-	return rPCAComponent{l, mat64.Sum(penalizedDiag) * penalty}
+	rank := 0
+	for _, v := range penalizedValues {
+		if v != 0 {
+			rank++
+		}
+	}
+	return rPCAComponent{
+		matrix: l,
+		norm:   mat64.Sum(penalizedDiag) * penalty,
+		rank:   rank,
+	}
 }
 
-func computeE(mat, l, s mat64.Matrix) rPCAComponent {
+// computeE returns the noise left over by L and S. Its norm is the squared
+// Frobenius norm, or its Huber analogue when delta is finite, with every cell
+// scaled by its weight if weights is not nil.
+func computeE(mat, l, s mat64.Matrix, delta float64, weights *mat64.Dense) rPCAComponent {
 	r, c := mat.Dims()
 	e := mat64.NewDense(r, c, nil)
 	e.Sub(mat, l)
 	e.Sub(e, s)
-	return rPCAComponent{e, math.Pow(mat64.Norm(e, 2), 2)}
+	if !math.IsInf(delta, 1) || weights != nil {
+		return rPCAComponent{matrix: e, norm: lossNorm(e, delta, weights)}
+	}
+	return rPCAComponent{matrix: e, norm: math.Pow(mat64.Norm(e, 2), 2)}
 }
 
-//TODO Make these one function
+// TODO Make these one function
 func softThresholdMat(mat mat64.Matrix, penalty float64) rPCAable {
 	r, c := mat.Dims()
 	thresholdedMat := mat64.NewDense(r, c, nil)
@@ -275,6 +576,52 @@ func softThresholdMat(mat mat64.Matrix, penalty float64) rPCAable {
 	return thresholdedMat
 }
 
+// softThresholdWeighted soft thresholds every value of the matrix by the
+// penalty divided by its weight. Values with a weight of zero are zeroed.
+func softThresholdWeighted(mat mat64.Matrix, penalty float64, weights mat64.Matrix) rPCAable {
+	r, c := mat.Dims()
+	thresholdedMat := mat64.NewDense(r, c, nil)
+	penalize := func(i, j int, v float64) float64 {
+		w := weights.At(i, j)
+		if w == 0 {
+			return 0
+		}
+		return signum(v) * math.Max(math.Abs(v)-penalty/w, 0)
+	}
+	thresholdedMat.Apply(penalize, mat)
+	return thresholdedMat
+}
+
+// softThresholdCols shrinks the L2 norm of every column of the matrix by the
+// penalty, zeroing columns whose norm is below it. If weights is not nil, the
+// norm of every column is taken after scaling its values by their weights, and
+// cells with no weight are left at zero, as in softThresholdWeighted.
+func softThresholdCols(mat mat64.Matrix, penalty float64, weights *mat64.Dense) *mat64.Dense {
+	r, c := mat.Dims()
+	thresholdedMat := mat64.NewDense(r, c, nil)
+	for j := 0; j < c; j++ {
+		col := mat64.Col(nil, j, mat)
+		weighted := col
+		if weights != nil {
+			weighted = mat64.Col(nil, j, weights)
+			for i, v := range col {
+				weighted[i] *= v
+			}
+		}
+		norm := math.Sqrt(sumSquares(weighted))
+		if norm <= penalty {
+			continue
+		}
+		for i, v := range col {
+			if weights != nil && weights.At(i, j) == 0 {
+				continue
+			}
+			thresholdedMat.Set(i, j, v*(1-penalty/norm))
+		}
+	}
+	return thresholdedMat
+}
+
 func softThresholdVec(v []float64, penalty float64) []float64 {
 	var thresholded []float64
 	penalize := func(v float64) float64 {
@@ -0,0 +1,95 @@
+package rpca
+
+import (
+	"log/slog"
+	"math"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// Parameters of the inexact ALM solver, as suggested by Lin, Chen and Ma in
+// "The Augmented Lagrange Multiplier Method for Exact Recovery of Corrupted
+// Low-Rank Matrices".
+const (
+	almTolerance = 1e-7
+	almRho       = 1.5
+	almMuScale   = 1.25
+	almMuCeiling = 1e7
+)
+
+// solveALM solves Principal Component Pursuit,
+//
+//	minimize ||L||_* + lambda ||S||_1 subject to L + S = M,
+//
+// with inexact augmented Lagrange multipliers. The default S penalty of
+// 1.4/sqrt(max(rows, cols)) is divided by 1.4 so that lambda defaults to the
+// 1/sqrt(max(rows, cols)) recommended by Candes et al.
+func solveALM(mat rPCAable, conf *rpcaConfig, logger *slog.Logger) solution {
+	rows, cols := mat.Dims()
+	lambda := conf.sPenalty / 1.4
+
+	norm2 := spectralNorm(mat)
+	normF := mat64.Norm(mat, 2)
+	y := mat64.NewDense(rows, cols, nil)
+	y.Scale(1/math.Max(norm2, maxAbs(mat)/lambda), mat)
+	mu := almMuScale / norm2
+	muCeiling := mu * almMuCeiling
+
+	if logger != nil {
+		logger.Debug("rpca: initial state",
+			"rows", rows,
+			"cols", cols,
+			"lambda", lambda,
+			"mu", mu,
+			"matrix", mat64.Formatted(mat))
+	}
+
+	l := mat64.NewDense(rows, cols, nil)
+	s := mat64.NewDense(rows, cols, nil)
+	e := mat64.NewDense(rows, cols, nil)
+	shifted := mat64.NewDense(rows, cols, nil)
+	step := mat64.NewDense(rows, cols, nil)
+	iter := 0
+	converged := false
+
+	for !converged && iter < MAX_ITERS {
+		thisLPenalty := conf.lPenalty / mu
+		thisSPenalty := lambda / mu
+
+		shifted.Scale(1/mu, y)
+		shifted.Add(shifted, mat)
+		lComp := computeL(shifted, s, thisLPenalty)
+		l = lComp.matrix
+		sComp := computeS(shifted, l, thisSPenalty, conf.outlierPursuit, nil)
+		s = sComp.matrix
+		eComp := computeE(mat, l, s, math.Inf(1), nil)
+		e = eComp.matrix
+
+		step.Scale(mu, e)
+		y.Add(y, step)
+
+		residual := math.Sqrt(eComp.norm) / normF
+		converged = residual < almTolerance
+
+		// Undo the 1/mu in the thresholds so the norms are on the scale of
+		// the objective.
+		stats := IterationStats{
+			Iteration:  iter,
+			Mu:         mu,
+			LPenalty:   thisLPenalty,
+			SPenalty:   thisSPenalty,
+			LNorm:      lComp.norm * mu,
+			SNorm:      sComp.norm * mu,
+			ENorm:      eComp.norm,
+			Objective:  (lComp.norm + sComp.norm) * mu,
+			Difference: residual,
+			Rank:       lComp.rank,
+			Sparsity:   sparsity(s),
+		}
+		conf.report(stats, logger)
+
+		mu = math.Min(mu*almRho, muCeiling)
+		iter++
+	}
+	return solution{l, s, e, converged, iter}
+}
@@ -0,0 +1,43 @@
+package rpca
+
+import (
+	"math"
+	"testing"
+)
+
+// lowRankSeries returns a noiseless series whose folded matrix has rank one:
+// a weekly profile that grows from period to period.
+func lowRankSeries(periods int) []float64 {
+	profile := []float64{5, 8, 9, 7, 6, 2, 1}
+	series := make([]float64, 7*periods)
+	for i := range series {
+		series[i] = (1 + 0.05*float64(i/7)) * profile[i%7]
+	}
+	return series
+}
+
+func TestSolveALM(t *testing.T) {
+	clean := lowRankSeries(20)
+	series := append([]float64(nil), clean...)
+	series[30] += 20
+	series[101] -= 15
+	conf := newConfig(series, []func(*rpcaConfig) error{
+		AutoDiff(false), Scale(false), Solver(InexactALM)})
+	decomposed := computeRPCA(buildMatrix(series, 7), &conf)
+	if !decomposed.converged {
+		t.Errorf("Expected inexact ALM to converge, but it ran %v iterations",
+			decomposed.iterations)
+	}
+	l := decomposed.unfold(decomposed.L)
+	s := decomposed.unfold(decomposed.S)
+	for i := range series {
+		if math.Abs(l[i]-clean[i]) > 0.01 {
+			t.Errorf("Failed recovering low-rank point %v. Expected %v but got %v",
+				i, clean[i], l[i])
+		}
+		if math.Abs(s[i]-(series[i]-clean[i])) > 0.01 {
+			t.Errorf("Failed recovering sparse point %v. Expected %v but got %v",
+				i, series[i]-clean[i], s[i])
+		}
+	}
+}
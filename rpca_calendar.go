@@ -0,0 +1,219 @@
+package rpca
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Calendar is a set of holidays, used with the Holidays option.
+type Calendar struct {
+	holidays map[date]string
+}
+
+// date is a calendar day, independent of any time zone.
+type date struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+func dateOf(t time.Time) date {
+	y, m, d := t.Date()
+	return date{y, m, d}
+}
+
+// NewCalendar returns an empty calendar.
+func NewCalendar() *Calendar {
+	return &Calendar{holidays: make(map[date]string)}
+}
+
+// Add makes the day of t, in t's location, a holiday with the given name.
+func (c *Calendar) Add(t time.Time, name string) {
+	c.holidays[dateOf(t)] = name
+}
+
+// Holiday returns the name of the holiday on the day of t, in t's location,
+// and whether there is one.
+func (c *Calendar) Holiday(t time.Time) (string, bool) {
+	name, ok := c.holidays[dateOf(t)]
+	return name, ok
+}
+
+// on returns which of the given timestamps fall on a holiday.
+func (c *Calendar) on(timestamps []time.Time) []bool {
+	holidays := make([]bool, len(timestamps))
+	for i, t := range timestamps {
+		_, holidays[i] = c.Holiday(t)
+	}
+	return holidays
+}
+
+/*
+ParseCalendar reads a calendar with one holiday per line: the date as
+YYYY-MM-DD, optionally followed by whitespace and the holiday's name. Blank lines
+and lines starting with # are ignored. For example:
+
+	# Company shutdown
+	2016-12-26 Boxing Day
+	2016-12-27
+*/
+func ParseCalendar(r io.Reader) (*Calendar, error) {
+	calendar := NewCalendar()
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		day, err := time.Parse("2006-01-02", fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", line, err)
+		}
+		calendar.Add(day, strings.Join(fields[1:], " "))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return calendar, nil
+}
+
+// LoadCalendar reads a calendar from the file at path, in the format described
+// by ParseCalendar.
+func LoadCalendar(path string) (*Calendar, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseCalendar(file)
+}
+
+/*
+RegionCalendar returns the public holidays of a region for the years from
+first to last, inclusive. The supported regions are:
+
+	US  United States federal holidays
+	GB  bank holidays in England and Wales
+	DE  national public holidays in Germany
+
+Holidays are listed on the day they fall on; substitute days off for holidays
+on a weekend are not included. It panics if the region is not supported.
+*/
+func RegionCalendar(region string, first, last int) *Calendar {
+	rules, ok := regions[region]
+	if !ok {
+		panic("Unknown calendar region")
+	}
+	calendar := NewCalendar()
+	for year := first; year <= last; year++ {
+		for _, rule := range rules {
+			if day, ok := rule.date(year); ok {
+				calendar.Add(day, rule.name)
+			}
+		}
+	}
+	return calendar
+}
+
+// holidayRule gives the date of a holiday in a year, if it is held that year.
+type holidayRule struct {
+	name string
+	date func(year int) (time.Time, bool)
+}
+
+var regions = map[string][]holidayRule{
+	"US": {
+		{"New Year's Day", fixedDate(time.January, 1)},
+		{"Martin Luther King Jr. Day", nthWeekday(time.January, time.Monday, 3)},
+		{"Washington's Birthday", nthWeekday(time.February, time.Monday, 3)},
+		{"Memorial Day", nthWeekday(time.May, time.Monday, -1)},
+		{"Juneteenth", since(2021, fixedDate(time.June, 19))},
+		{"Independence Day", fixedDate(time.July, 4)},
+		{"Labor Day", nthWeekday(time.September, time.Monday, 1)},
+		{"Columbus Day", nthWeekday(time.October, time.Monday, 2)},
+		{"Veterans Day", fixedDate(time.November, 11)},
+		{"Thanksgiving Day", nthWeekday(time.November, time.Thursday, 4)},
+		{"Christmas Day", fixedDate(time.December, 25)},
+	},
+	"GB": {
+		{"New Year's Day", fixedDate(time.January, 1)},
+		{"Good Friday", easterOffset(-2)},
+		{"Easter Monday", easterOffset(1)},
+		{"Early May Bank Holiday", nthWeekday(time.May, time.Monday, 1)},
+		{"Spring Bank Holiday", nthWeekday(time.May, time.Monday, -1)},
+		{"Summer Bank Holiday", nthWeekday(time.August, time.Monday, -1)},
+		{"Christmas Day", fixedDate(time.December, 25)},
+		{"Boxing Day", fixedDate(time.December, 26)},
+	},
+	"DE": {
+		{"Neujahr", fixedDate(time.January, 1)},
+		{"Karfreitag", easterOffset(-2)},
+		{"Ostermontag", easterOffset(1)},
+		{"Tag der Arbeit", fixedDate(time.May, 1)},
+		{"Christi Himmelfahrt", easterOffset(39)},
+		{"Pfingstmontag", easterOffset(50)},
+		{"Tag der Deutschen Einheit", fixedDate(time.October, 3)},
+		{"Erster Weihnachtstag", fixedDate(time.December, 25)},
+		{"Zweiter Weihnachtstag", fixedDate(time.December, 26)},
+	},
+}
+
+func fixedDate(month time.Month, day int) func(int) (time.Time, bool) {
+	return func(year int) (time.Time, bool) {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC), true
+	}
+}
+
+// nthWeekday returns the n-th given weekday of a month, or the last one if n
+// is -1.
+func nthWeekday(month time.Month, weekday time.Weekday, n int) func(int) (time.Time, bool) {
+	return func(year int) (time.Time, bool) {
+		if n == -1 {
+			last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+			back := (int(last.Weekday()) - int(weekday) + 7) % 7
+			return last.AddDate(0, 0, -back), true
+		}
+		first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+		forward := (int(weekday) - int(first.Weekday()) + 7) % 7
+		return first.AddDate(0, 0, forward+7*(n-1)), true
+	}
+}
+
+// easterOffset returns the day the given number of days after Easter Sunday.
+func easterOffset(days int) func(int) (time.Time, bool) {
+	return func(year int) (time.Time, bool) {
+		return easter(year).AddDate(0, 0, days), true
+	}
+}
+
+// since restricts a rule to the years from first on.
+func since(first int, rule func(int) (time.Time, bool)) func(int) (time.Time, bool) {
+	return func(year int) (time.Time, bool) {
+		if year < first {
+			return time.Time{}, false
+		}
+		return rule(year)
+	}
+}
+
+// easter returns Easter Sunday in the Gregorian calendar, using the anonymous
+// Gregorian algorithm.
+func easter(year int) time.Time {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,104 @@
+package rpca
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegionCalendar(t *testing.T) {
+	tests := []struct {
+		region string
+		day    time.Time
+		name   string
+	}{
+		{"US", time.Date(2016, time.May, 30, 0, 0, 0, 0, time.UTC), "Memorial Day"},
+		{"US", time.Date(2016, time.November, 24, 0, 0, 0, 0, time.UTC), "Thanksgiving Day"},
+		{"US", time.Date(2016, time.January, 18, 0, 0, 0, 0, time.UTC), "Martin Luther King Jr. Day"},
+		{"GB", time.Date(2016, time.March, 25, 0, 0, 0, 0, time.UTC), "Good Friday"},
+		{"GB", time.Date(2016, time.August, 29, 0, 0, 0, 0, time.UTC), "Summer Bank Holiday"},
+		{"DE", time.Date(2016, time.May, 16, 0, 0, 0, 0, time.UTC), "Pfingstmontag"},
+		{"DE", time.Date(2017, time.April, 14, 0, 0, 0, 0, time.UTC), "Karfreitag"},
+	}
+	for _, test := range tests {
+		calendar := RegionCalendar(test.region, 2015, 2017)
+		name, ok := calendar.Holiday(test.day)
+		if !ok || name != test.name {
+			t.Errorf("Failed %v on %v. Expected %v but got %q",
+				test.region, test.day.Format("2006-01-02"), test.name, name)
+		}
+	}
+	if _, ok := RegionCalendar("US", 2016, 2016).Holiday(
+		time.Date(2016, time.June, 19, 0, 0, 0, 0, time.UTC)); ok {
+		t.Errorf("Expected no Juneteenth holiday before 2021")
+	}
+}
+
+func TestParseCalendar(t *testing.T) {
+	calendar, err := ParseCalendar(strings.NewReader(
+		"# Company shutdown\n\n2016-12-26 Boxing Day\n2016-12-27\n"))
+	if err != nil {
+		t.Fatalf("Expected calendar to parse, but got %v", err)
+	}
+	if name, ok := calendar.Holiday(time.Date(2016, time.December, 26, 9, 0, 0, 0,
+		time.UTC)); !ok || name != "Boxing Day" {
+		t.Errorf("Expected Boxing Day on 2016-12-26 but got %q", name)
+	}
+	if _, ok := calendar.Holiday(time.Date(2016, time.December, 27, 0, 0, 0, 0,
+		time.UTC)); !ok {
+		t.Errorf("Expected an unnamed holiday on 2016-12-27")
+	}
+	if _, err := ParseCalendar(strings.NewReader("2016-12-26\n12/27/2016\n")); err == nil ||
+		!strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("Expected an error on line 2, but got %v", err)
+	}
+}
+
+func TestHolidays(t *testing.T) {
+	series := seasonalSeries(84)
+	start := time.Date(2016, time.January, 4, 0, 0, 0, 0, time.UTC)
+	timestamps := make([]time.Time, len(series))
+	for i := range timestamps {
+		timestamps[i] = start.AddDate(0, 0, i)
+	}
+	calendar := NewCalendar()
+	holidays := []int{10, 30, 50, 71}
+	for _, i := range holidays {
+		series[i] = 3
+		calendar.Add(timestamps[i], "")
+	}
+	series[45] += 50
+
+	anoms := FindAnomalies(series, AutoDiff(false), Timestamps(timestamps))
+	if !anoms.Positions[holidays[0]] {
+		t.Errorf("Expected holidays to be flagged without a calendar")
+	}
+	for _, mode := range []HolidayMode{MaskHolidays, HolidayPattern} {
+		anoms := FindAnomalies(series, AutoDiff(false), Timestamps(timestamps),
+			Holidays(calendar, mode))
+		for _, i := range holidays {
+			if anoms.Positions[i] {
+				t.Errorf("Failed mode %v. Expected holiday %v not to be flagged", mode, i)
+			}
+			if (mode == MaskHolidays) != (anoms.Excluded != nil && anoms.Excluded[i]) {
+				t.Errorf("Failed mode %v. Expected holiday %v to be excluded only "+
+					"when masking", mode, i)
+			}
+		}
+		if !anoms.Positions[45] {
+			t.Errorf("Failed mode %v. Expected spike at 45 to be flagged", mode)
+		}
+	}
+
+	series[50] = 25
+	anoms = FindAnomalies(series, AutoDiff(false), Timestamps(timestamps),
+		Holidays(calendar, HolidayPattern))
+	if !anoms.Positions[50] {
+		t.Errorf("Expected a busy holiday to be flagged against the holiday pattern")
+	}
+	for _, i := range []int{10, 30, 71} {
+		if anoms.Positions[i] {
+			t.Errorf("Expected holiday %v not to be flagged", i)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package rpca
+
+type Cleaned struct {
+	// Values is the provided time series with anomalies removed, in the domain
+	// of the provided time series.
+	Values []float64
+
+	// A slice of booleans indicating which values differ from the provided time
+	// series.
+	Changed []bool
+}
+
+/*
+Clean removes anomalies from the given time series, for example to prepare
+historical data for a forecasting model that should not learn from incidents.
+It takes the same options as FindAnomalies.
+
+Anomalous points are replaced by the low-rank component (L), which is what the
+series would have looked like without the anomaly. With the KeepNoise option,
+the noise component (E) is added back so cleaned stretches are not unnaturally
+smooth.
+
+If the series was differenced, anomalies describe changes from one point to the
+next, so their removal carries forward: a spike is removed from the single point
+it affects, while a lasting level shift is removed from every point after it.
+The noise removed along with an anomalous change carries forward with it.
+*/
+func Clean(series []float64, options ...func(*rpcaConfig) error) Cleaned {
+	conf := newConfig(series, options)
+	transformed := conf.transform(series)
+	decomposed := decompose(transformed, &conf)
+
+	s := decomposed.unfold(decomposed.S)
+	e := decomposed.unfold(decomposed.E)
+	cleaned := Cleaned{
+		Values:  make([]float64, len(series)),
+		Changed: make([]bool, len(series)),
+	}
+	if decomposed.differenced {
+		// The first value only pads the differenced series, so there is nothing
+		// to remove there.
+		s[0], e[0] = 0, 0
+	}
+	removed := 0.0
+	for i, v := range transformed {
+		change := s[i]
+		if s[i] != 0 && !conf.keepNoise {
+			change += e[i]
+		}
+		if decomposed.differenced {
+			removed += change
+		} else {
+			removed = change
+		}
+		value := v - removed
+		cleaned.Values[i] = series[i]
+		if value != v {
+			cleaned.Values[i] = conf.untransform(value)
+		}
+		cleaned.Changed[i] = cleaned.Values[i] != series[i]
+	}
+	return cleaned
+}
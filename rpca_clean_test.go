@@ -0,0 +1,52 @@
+package rpca
+
+import (
+	"math"
+	"testing"
+)
+
+func TestClean(t *testing.T) {
+	for _, diff := range []bool{false, true} {
+		series := seasonalSeries(56)
+		series[30] += 50
+		cleaned := Clean(series, AutoDiff(false), ForceDiff(diff))
+		if !cleaned.Changed[30] {
+			t.Errorf("Expected spike to be changed with differencing %v", diff)
+		}
+		expected := 10 + 5*math.Sin(2*math.Pi*30/7)
+		if math.Abs(cleaned.Values[30]-expected) > 1 {
+			t.Errorf("Failed cleaning spike with differencing %v. Expected about %v "+
+				"but got %v", diff, expected, cleaned.Values[30])
+		}
+		for i := 40; i < 56; i++ {
+			if math.Abs(cleaned.Values[i]-series[i]) > 1 {
+				t.Errorf("Expected point %v to be left about alone with differencing "+
+					"%v, but it went from %v to %v", i, diff, series[i], cleaned.Values[i])
+			}
+		}
+	}
+}
+
+func TestCleanDifferencedCarriesForward(t *testing.T) {
+	series := seasonalSeries(56)
+	series[30] += 50
+	for i := 45; i < 56; i++ {
+		series[i] += 20
+	}
+	options := []func(*rpcaConfig) error{AutoDiff(false), ForceDiff(true)}
+	cleaned := Clean(series, options...)
+	anoms := FindAnomalies(series, options...)
+	// Between anomalous changes, the cleaned series must follow the original
+	// one exactly, offset by all that was removed so far.
+	for i := 1; i < len(series); i++ {
+		if anoms.Positions[i] {
+			continue
+		}
+		offset := series[i] - cleaned.Values[i]
+		previous := series[i-1] - cleaned.Values[i-1]
+		if math.Abs(offset-previous) > 1e-9 {
+			t.Errorf("Expected the removal at %v to carry forward to %v, but it "+
+				"went from %v to %v", i-1, i, previous, offset)
+		}
+	}
+}
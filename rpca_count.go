@@ -0,0 +1,57 @@
+package rpca
+
+import "math"
+
+// anscombe is the Anscombe transform, which turns Poisson counts into values
+// that are roughly normal with unit variance, whatever the rate.
+func anscombe(count float64) float64 {
+	if count < 0 {
+		panic("Negative count")
+	}
+	return 2 * math.Sqrt(count+3.0/8)
+}
+
+// inverseAnscombe maps a transformed value back to a count, which is never
+// negative.
+func inverseAnscombe(v float64) float64 {
+	if v <= 0 {
+		return 0
+	}
+	return math.Max(0, v*v/4-3.0/8)
+}
+
+// transform returns the series the decomposition runs on: the series itself,
+// or its Anscombe transform with the Counts option.
+func (conf *rpcaConfig) transform(series []float64) []float64 {
+	if !conf.counts {
+		return series
+	}
+	transformed := make([]float64, len(series))
+	for i, v := range series {
+		transformed[i] = anscombe(v)
+	}
+	return transformed
+}
+
+// untransform maps a value of the series the decomposition ran on back to the
+// domain of the provided series.
+func (conf *rpcaConfig) untransform(v float64) float64 {
+	if !conf.counts {
+		return v
+	}
+	return inverseAnscombe(v)
+}
+
+// countDeviations maps deviations of the transformed series back to counts: a
+// point whose transformed value was d above what was expected deviates from
+// the expected count by its count less the inverse of its transformed value
+// less d.
+func countDeviations(series, transformed, deviations []float64) []float64 {
+	counts := make([]float64, len(deviations))
+	for i, d := range deviations {
+		if d != 0 {
+			counts[i] = series[i] - inverseAnscombe(transformed[i]-d)
+		}
+	}
+	return counts
+}
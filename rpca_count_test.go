@@ -0,0 +1,73 @@
+package rpca
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// poisson draws a Poisson distributed count with the given rate.
+func poisson(r *rand.Rand, rate float64) float64 {
+	if rate > 30 {
+		return math.Max(0, math.Round(rate+math.Sqrt(rate)*r.NormFloat64()))
+	}
+	limit, product, count := math.Exp(-rate), r.Float64(), 0.0
+	for product > limit {
+		product *= r.Float64()
+		count++
+	}
+	return count
+}
+
+func TestAnscombe(t *testing.T) {
+	for _, count := range []float64{0, 1, 3, 250} {
+		if observed := inverseAnscombe(anscombe(count)); math.Abs(observed-count) > 1e-9 {
+			t.Errorf("Failed inverting the Anscombe transform of %v, got %v",
+				count, observed)
+		}
+	}
+	if inverseAnscombe(-1) != 0 {
+		t.Errorf("Expected negative values to map to a count of zero")
+	}
+}
+
+func TestCounts(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	series := make([]float64, 24*14)
+	for i := range series {
+		// Quiet at night, busy in the afternoon.
+		rate := 0.05
+		if hour := i % 24; hour >= 12 && hour < 18 {
+			rate = 250
+		}
+		series[i] = poisson(r, rate)
+	}
+	quiet, busy := 24*5+2, 24*9+14
+	series[quiet], series[busy] = 3, 320
+
+	gaussian := FindAnomalies(series, Frequency(24), AutoDiff(false))
+	if gaussian.NormedValues[quiet] > gaussian.NormedValues[busy]/10 {
+		t.Errorf("Expected 3 events in a quiet hour to be dwarfed by the busy "+
+			"hour without the Counts option, but got %v and %v",
+			gaussian.NormedValues[quiet], gaussian.NormedValues[busy])
+	}
+	counts := FindAnomalies(series, Frequency(24), AutoDiff(false), Counts(true))
+	for _, i := range []int{quiet, busy} {
+		if !counts.Positions[i] {
+			t.Errorf("Expected %v events at %v to be flagged", series[i], i)
+		}
+	}
+	if v := counts.Values[busy]; v < 40 || v > 80 {
+		t.Errorf("Expected the busy hour to be about 70 events above normal, "+
+			"but got %v", v)
+	}
+	if v := counts.Values[quiet]; v < 2 || v > 3 {
+		t.Errorf("Expected the quiet hour to be about 3 events above normal, "+
+			"but got %v", v)
+	}
+	if counts.NormedValues[quiet] < counts.NormedValues[busy]/3 {
+		t.Errorf("Expected the quiet hour to be scored on a scale comparable to "+
+			"the busy hour, but got %v and %v", counts.NormedValues[quiet],
+			counts.NormedValues[busy])
+	}
+}
@@ -0,0 +1,296 @@
+package rpca
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fact is a row of a fact table: the value of a metric for one combination of
+// dimension values, such as country and device, at one time.
+type Fact struct {
+	Dimensions map[string]string
+	Time       time.Time
+	Value      float64
+}
+
+// Segment selects the facts with the given value for each of its dimensions.
+// The empty segment selects every fact.
+type Segment map[string]string
+
+// String returns the segment as comma separated dimension=value pairs, sorted
+// by dimension, or "total" for the empty segment.
+func (s Segment) String() string {
+	if len(s) == 0 {
+		return "total"
+	}
+	pairs := make([]string, 0, len(s))
+	for dimension, value := range s {
+		pairs = append(pairs, dimension+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (s Segment) contains(fact Fact) bool {
+	for dimension, value := range s {
+		if fact.Dimensions[dimension] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Cube aggregates a fact table into one time series per segment, so that
+// anomalies in the total can be drilled down into the segments that explain
+// them.
+type Cube struct {
+	// Times holds the distinct times of the facts in increasing order. Every
+	// series of the cube has one point per time.
+	Times []time.Time
+
+	dimensions []string
+	facts      []Fact
+	index      map[int64]int
+}
+
+// NewCube builds a cube from a fact table. Facts may come in any order, and
+// several facts may share a segment and time, in which case they are summed.
+func NewCube(facts []Fact) *Cube {
+	cube := &Cube{facts: facts, index: make(map[int64]int)}
+	dimensions := make(map[string]bool)
+	for _, fact := range facts {
+		for dimension := range fact.Dimensions {
+			dimensions[dimension] = true
+		}
+		if _, ok := cube.index[fact.Time.UnixNano()]; !ok {
+			cube.index[fact.Time.UnixNano()] = 0
+			cube.Times = append(cube.Times, fact.Time)
+		}
+	}
+	for dimension := range dimensions {
+		cube.dimensions = append(cube.dimensions, dimension)
+	}
+	sort.Strings(cube.dimensions)
+	sort.Slice(cube.Times, func(a, b int) bool {
+		return cube.Times[a].Before(cube.Times[b])
+	})
+	for i, t := range cube.Times {
+		cube.index[t.UnixNano()] = i
+	}
+	return cube
+}
+
+// Series returns the sum of the values of the facts in a segment at each of
+// the cube's times. Times without facts in the segment are zero.
+func (c *Cube) Series(segment Segment) []float64 {
+	series := make([]float64, len(c.Times))
+	for _, fact := range c.facts {
+		if segment.contains(fact) {
+			series[c.index[fact.Time.UnixNano()]] += fact.Value
+		}
+	}
+	return series
+}
+
+// Segments returns every segment that has facts and fixes between one and
+// depth dimensions, or every dimension if depth is zero or less. Segments are
+// ordered by the number of dimensions they fix, then by name.
+func (c *Cube) Segments(depth int) []Segment {
+	aggregates := c.aggregate(depth)
+	segments := make([]Segment, len(aggregates))
+	for i, a := range aggregates {
+		segments[i] = a.segment
+	}
+	return segments
+}
+
+// segmentSeries is the series of one segment.
+type segmentSeries struct {
+	segment Segment
+	series  []float64
+}
+
+// aggregate sums the facts into the series of every segment returned by
+// Segments, in the same order, in a single pass over the facts.
+func (c *Cube) aggregate(depth int) []*segmentSeries {
+	if depth <= 0 || depth > len(c.dimensions) {
+		depth = len(c.dimensions)
+	}
+	groups := subsets(c.dimensions, depth)
+	found := make(map[string]*segmentSeries)
+	for _, fact := range c.facts {
+		t := c.index[fact.Time.UnixNano()]
+		for _, dimensions := range groups {
+			segment := make(Segment, len(dimensions))
+			for _, dimension := range dimensions {
+				segment[dimension] = fact.Dimensions[dimension]
+			}
+			name := segment.String()
+			a, ok := found[name]
+			if !ok {
+				a = &segmentSeries{segment: segment,
+					series: make([]float64, len(c.Times))}
+				found[name] = a
+			}
+			a.series[t] += fact.Value
+		}
+	}
+	aggregates := make([]*segmentSeries, 0, len(found))
+	for _, a := range found {
+		aggregates = append(aggregates, a)
+	}
+	sort.Slice(aggregates, func(a, b int) bool {
+		sa, sb := aggregates[a].segment, aggregates[b].segment
+		if len(sa) != len(sb) {
+			return len(sa) < len(sb)
+		}
+		return sa.String() < sb.String()
+	})
+	return aggregates
+}
+
+// subsets returns the non-empty subsets of the given items with at most size
+// items.
+func subsets(items []string, size int) [][]string {
+	var result [][]string
+	var grow func(start int, current []string)
+	grow = func(start int, current []string) {
+		if len(current) > 0 {
+			result = append(result, append([]string(nil), current...))
+		}
+		if len(current) == size {
+			return
+		}
+		for i := start; i < len(items); i++ {
+			grow(i+1, append(current, items[i]))
+		}
+	}
+	grow(0, nil)
+	return result
+}
+
+// SegmentAnomalies are the anomalies found in the series of one segment.
+type SegmentAnomalies struct {
+	Segment   Segment
+	Series    []float64
+	Anomalies Anomalies
+
+	// Err is why detection failed on the segment, such as a series too short
+	// for the options, or nil if it did not.
+	Err error
+}
+
+// Explanation is a segment that explains part of an anomaly in the total.
+type Explanation struct {
+	Segment Segment
+
+	// Value is how anomalous the segment was at the time, as in
+	// Anomalies.Values.
+	Value float64
+
+	// Share is the fraction of the anomaly in the total that the segment's
+	// anomaly accounts for.
+	Share float64
+}
+
+type CubeAnomalies struct {
+	// Total holds the anomalies found in the total of every fact.
+	Total SegmentAnomalies
+
+	// Segments holds the anomalies found in every segment, in the order of
+	// Cube.Segments.
+	Segments []SegmentAnomalies
+
+	// Explanations holds, for every anomalous point of the total, the segments
+	// that were anomalous in the same direction at the same time, ranked by
+	// the share of the total's anomaly they account for. A segment and a
+	// narrower segment within it, such as country=US and
+	// country=US,device=tv, can both explain the same anomaly; the narrower
+	// one localizes it further if its share is nearly as large.
+	Explanations map[int][]Explanation
+}
+
+/*
+FindAnomalies runs FindAnomalies on the total and on every segment of the cube
+that fixes up to depth dimensions, or every dimension if depth is zero or less,
+and ranks the segments that explain each anomaly found in the total. Series are
+decomposed in parallel, one per available CPU. It takes the same options as
+FindAnomalies, plus the Timestamps option set to the cube's times. Options that
+take a callback, such as OnIteration, have it called concurrently. If
+detection panics on a segment, the panic is recovered and reported in the
+segment's Err, and the segment explains nothing.
+*/
+func (c *Cube) FindAnomalies(depth int, options ...func(*rpcaConfig) error) CubeAnomalies {
+	aggregates := append([]*segmentSeries{{segment: Segment{},
+		series: c.Series(Segment{})}}, c.aggregate(depth)...)
+	options = append([]func(*rpcaConfig) error{Timestamps(c.Times)}, options...)
+
+	results := make([]SegmentAnomalies, len(aggregates))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = findSegmentAnomalies(aggregates[i], options)
+			}
+		}()
+	}
+	for i := range aggregates {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	anomalies := CubeAnomalies{
+		Total:        results[0],
+		Segments:     results[1:],
+		Explanations: make(map[int][]Explanation),
+	}
+	for t, total := range anomalies.Total.Anomalies.Values {
+		if total == 0 {
+			continue
+		}
+		var explanations []Explanation
+		for _, segment := range anomalies.Segments {
+			if segment.Err != nil {
+				continue
+			}
+			v := segment.Anomalies.Values[t]
+			if v == 0 || math.Signbit(v) != math.Signbit(total) {
+				continue
+			}
+			explanations = append(explanations, Explanation{
+				Segment: segment.Segment,
+				Value:   v,
+				Share:   v / total,
+			})
+		}
+		sort.SliceStable(explanations, func(a, b int) bool {
+			return explanations[a].Share > explanations[b].Share
+		})
+		anomalies.Explanations[t] = explanations
+	}
+	return anomalies
+}
+
+// findSegmentAnomalies runs FindAnomalies on the series of a segment,
+// recovering from any panic as the segment's error.
+func findSegmentAnomalies(a *segmentSeries,
+	options []func(*rpcaConfig) error) (result SegmentAnomalies) {
+	result = SegmentAnomalies{Segment: a.segment, Series: a.series}
+	defer func() {
+		if r := recover(); r != nil {
+			result.Anomalies = Anomalies{}
+			result.Err = fmt.Errorf("%v", r)
+		}
+	}()
+	result.Anomalies = FindAnomalies(a.series, options...)
+	return result
+}
@@ -0,0 +1,96 @@
+package rpca
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCube(t *testing.T) {
+	start := time.Date(2016, time.January, 4, 0, 0, 0, 0, time.UTC)
+	var facts []Fact
+	for _, country := range []string{"US", "GB"} {
+		for _, device := range []string{"tv", "phone"} {
+			for i, v := range seasonalSeries(56) {
+				if country == "US" && device == "tv" && i == 30 {
+					v -= 30
+				}
+				facts = append(facts, Fact{
+					Dimensions: map[string]string{"country": country, "device": device},
+					Time:       start.AddDate(0, 0, i),
+					Value:      v,
+				})
+			}
+		}
+	}
+	cube := NewCube(facts)
+	if len(cube.Times) != 56 {
+		t.Fatalf("Expected 56 times but got %v", len(cube.Times))
+	}
+	if segments := cube.Segments(0); len(segments) != 8 {
+		t.Errorf("Expected 8 segments but got %v", len(segments))
+	}
+	if segments := cube.Segments(1); len(segments) != 4 ||
+		segments[0].String() != "country=GB" {
+		t.Errorf("Expected 4 segments of one dimension, starting with "+
+			"country=GB, but got %v", segments)
+	}
+	if v := cube.Series(Segment{"device": "tv"})[0]; math.Abs(v-20) > 1 {
+		t.Errorf("Expected the tv series to sum both countries, but got %v", v)
+	}
+
+	anoms := cube.FindAnomalies(0, AutoDiff(false))
+	if !anoms.Total.Anomalies.Positions[30] {
+		t.Fatalf("Expected the drop at 30 to be flagged in the total")
+	}
+	explanations := anoms.Explanations[30]
+	if len(explanations) == 0 {
+		t.Fatalf("Expected the drop at 30 to be explained")
+	}
+	for _, explanation := range explanations {
+		if explanation.Segment.contains(Fact{Dimensions: map[string]string{
+			"country": "GB", "device": "phone"}}) {
+			t.Errorf("Expected %v not to explain the drop", explanation.Segment)
+		}
+		if explanation.Share < 0.5 {
+			t.Errorf("Expected %v to explain most of the drop, but got a share "+
+				"of %v", explanation.Segment, explanation.Share)
+		}
+	}
+}
+
+func TestCubeSegmentErrors(t *testing.T) {
+	start := time.Date(2016, time.January, 4, 0, 0, 0, 0, time.UTC)
+	var facts []Fact
+	for i, v := range seasonalSeries(56) {
+		for kind, value := range map[string]float64{"sale": 10 * v, "refund": -v} {
+			facts = append(facts, Fact{
+				Dimensions: map[string]string{"kind": kind},
+				Time:       start.AddDate(0, 0, i),
+				Value:      value,
+			})
+		}
+	}
+	cube := NewCube(facts)
+	for _, a := range cube.aggregate(0) {
+		series := cube.Series(a.segment)
+		for i := range series {
+			if math.Abs(series[i]-a.series[i]) > 1e-9 {
+				t.Fatalf("Expected the aggregate of %v to match its series", a.segment)
+			}
+		}
+	}
+
+	// Counts must not be negative, so detection fails on refunds alone.
+	anoms := cube.FindAnomalies(0, AutoDiff(false), Counts(true))
+	if anoms.Total.Err != nil {
+		t.Errorf("Expected the total to be decomposed but got %v", anoms.Total.Err)
+	}
+	for _, segment := range anoms.Segments {
+		failed := segment.Segment["kind"] == "refund"
+		if (segment.Err != nil) != failed {
+			t.Errorf("Expected %v to fail: %v, but got error %v", segment.Segment,
+				failed, segment.Err)
+		}
+	}
+}
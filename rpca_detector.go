@@ -0,0 +1,109 @@
+package rpca
+
+import "math"
+
+// Detector finds anomalies in a time series, so that detectors can be swapped,
+// compared and combined.
+type Detector interface {
+	Detect(series []float64) Anomalies
+}
+
+// DetectorFunc adapts a function to the Detector interface.
+type DetectorFunc func(series []float64) Anomalies
+
+// Detect calls f.
+func (f DetectorFunc) Detect(series []float64) Anomalies {
+	return f(series)
+}
+
+// RPCA returns a Detector that runs FindAnomalies with the given options.
+func RPCA(options ...func(*rpcaConfig) error) Detector {
+	return DetectorFunc(func(series []float64) Anomalies {
+		return FindAnomalies(series, options...)
+	})
+}
+
+// Combination is how an Ensemble combines what its detectors found.
+type Combination int
+
+const (
+	// Vote flags the points flagged by more than half of the detectors, with
+	// the mean of the values they found.
+	Vote Combination = iota
+
+	// MaxScore flags the points flagged by any detector, with the values
+	// found by whichever detector found the largest normed value there.
+	MaxScore
+
+	// Weighted flags the points flagged by detectors whose weights add up to
+	// more than half of the total weight, with the weighted mean of the values
+	// they found.
+	Weighted
+)
+
+// Ensemble is a Detector that runs several detectors on the same series and
+// combines what they found, since different detectors catch different kinds
+// of anomaly. Only the Positions, Values and NormedValues of the combined
+// anomalies are set.
+type Ensemble struct {
+	Detectors   []Detector
+	Combination Combination
+
+	// Weights holds the weight of each detector for the Weighted combination,
+	// or nil to weight them equally.
+	Weights []float64
+}
+
+// Detect runs every detector on the series and combines what they found.
+func (e Ensemble) Detect(series []float64) Anomalies {
+	if len(e.Detectors) == 0 {
+		panic("Ensemble without detectors")
+	}
+	weights := make([]float64, len(e.Detectors))
+	for i := range weights {
+		weights[i] = 1
+	}
+	if e.Combination == Weighted && e.Weights != nil {
+		weights = normalizeWeights(e.Weights, len(e.Detectors))
+	}
+	found := make([]Anomalies, len(e.Detectors))
+	total := 0.0
+	for i, detector := range e.Detectors {
+		found[i] = detector.Detect(series)
+		total += weights[i]
+	}
+
+	combined := Anomalies{
+		Positions:    make([]bool, len(series)),
+		Values:       make([]float64, len(series)),
+		NormedValues: make([]float64, len(series)),
+	}
+	for t := range series {
+		if e.Combination == MaxScore {
+			best := -1.0
+			for _, anomalies := range found {
+				if anomalies.Positions[t] && math.Abs(anomalies.NormedValues[t]) > best {
+					best = math.Abs(anomalies.NormedValues[t])
+					combined.Positions[t] = true
+					combined.Values[t] = anomalies.Values[t]
+					combined.NormedValues[t] = anomalies.NormedValues[t]
+				}
+			}
+			continue
+		}
+		votes, value, normed := 0.0, 0.0, 0.0
+		for i, anomalies := range found {
+			if anomalies.Positions[t] {
+				votes += weights[i]
+				value += weights[i] * anomalies.Values[t]
+				normed += weights[i] * anomalies.NormedValues[t]
+			}
+		}
+		if votes > total/2 {
+			combined.Positions[t] = true
+			combined.Values[t] = value / votes
+			combined.NormedValues[t] = normed / votes
+		}
+	}
+	return combined
+}
@@ -0,0 +1,58 @@
+package rpca
+
+import (
+	"math"
+	"testing"
+)
+
+// fixed returns a detector that flags the given points with the given normed
+// values, and values ten times as large.
+func fixed(values map[int]float64) Detector {
+	return DetectorFunc(func(series []float64) Anomalies {
+		return anomaliesAt(len(series), values)
+	})
+}
+
+func TestEnsemble(t *testing.T) {
+	detectors := []Detector{
+		fixed(map[int]float64{1: 2, 2: 1}),
+		fixed(map[int]float64{1: 4, 3: 1}),
+		fixed(map[int]float64{1: 3, 2: 5}),
+	}
+	tests := []struct {
+		description string
+		ensemble    Ensemble
+		normed      []float64
+	}{
+		{"vote", Ensemble{Detectors: detectors, Combination: Vote},
+			[]float64{0, 3, 3, 0}},
+		{"max score", Ensemble{Detectors: detectors, Combination: MaxScore},
+			[]float64{0, 4, 5, 1}},
+		{"weighted", Ensemble{Detectors: detectors, Combination: Weighted,
+			Weights: []float64{1, 3, 1}}, []float64{0, 3.4, 0, 1}},
+	}
+	for _, test := range tests {
+		anoms := test.ensemble.Detect(make([]float64, 4))
+		for i, expected := range test.normed {
+			if anoms.Positions[i] != (expected != 0) ||
+				math.Abs(anoms.NormedValues[i]-expected) > 1e-9 ||
+				math.Abs(anoms.Values[i]-10*expected) > 1e-9 {
+				t.Errorf("Failed '%v' on point %v. Expected a normed value of %v "+
+					"but got %v", test.description, i, expected, anoms.NormedValues[i])
+			}
+		}
+	}
+}
+
+func TestRPCAAndESDAgree(t *testing.T) {
+	series := seasonalSeries(56)
+	series[30] += 20
+	ensemble := Ensemble{
+		Detectors:   []Detector{RPCA(AutoDiff(false)), SeasonalESD(0.1)},
+		Combination: Vote,
+	}
+	anoms := ensemble.Detect(series)
+	if !anoms.Positions[30] {
+		t.Errorf("Expected both detectors to flag the spike at 30")
+	}
+}
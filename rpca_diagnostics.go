@@ -0,0 +1,88 @@
+package rpca
+
+import "math"
+
+// Diagnostics is a record of how the algorithm behaved while decomposing a time
+// series. It is useful for working out why detection looks wrong, for example
+// when the solver ran out of iterations.
+type Diagnostics struct {
+	// Whether the algorithm converged before hitting MAX_ITERS.
+	Converged bool
+
+	// The state of the solver after each iteration, in order.
+	Iterations []IterationStats
+}
+
+// ConvergenceIssue is a pattern in the solver's behavior that suggests the
+// result should not be trusted.
+type ConvergenceIssue int
+
+const (
+	// Oscillation means the objective kept going up and down instead of
+	// settling. This usually means the penalties are too aggressive for the
+	// data.
+	Oscillation ConvergenceIssue = iota + 1
+
+	// Stagnation means the solver ran out of iterations while the objective
+	// was barely moving. This usually means the series is poorly scaled.
+	Stagnation
+)
+
+func (issue ConvergenceIssue) String() string {
+	switch issue {
+	case Oscillation:
+		return "oscillation"
+	case Stagnation:
+		return "stagnation"
+	default:
+		return "unknown"
+	}
+}
+
+// The number of trailing iterations inspected when looking for issues.
+const issueWindow = 20
+
+// record hooks the configuration's iteration callback so that every iteration
+// is appended to the returned Diagnostics. Any callback the user provided is
+// still called.
+func (conf *rpcaConfig) record() *Diagnostics {
+	diagnostics := &Diagnostics{}
+	next := conf.onIteration
+	conf.onIteration = func(stats IterationStats) {
+		diagnostics.Iterations = append(diagnostics.Iterations, stats)
+		if next != nil {
+			next(stats)
+		}
+	}
+	return diagnostics
+}
+
+// Issues inspects the last iterations of the solver and returns any patterns
+// that suggest it did not settle on a good solution.
+func (d *Diagnostics) Issues() []ConvergenceIssue {
+	var issues []ConvergenceIssue
+	n := len(d.Iterations)
+	window := int(math.Min(issueWindow, float64(n)))
+	if window < 4 {
+		return issues
+	}
+	tail := d.Iterations[n-window:]
+
+	flips := 0
+	for i := 2; i < len(tail); i++ {
+		previous := tail[i-1].Objective - tail[i-2].Objective
+		current := tail[i].Objective - tail[i-1].Objective
+		if previous*current < 0 {
+			flips++
+		}
+	}
+	if float64(flips) >= 0.75*float64(len(tail)-2) {
+		issues = append(issues, Oscillation)
+	}
+
+	first, last := tail[0].Objective, tail[len(tail)-1].Objective
+	if !d.Converged && math.Abs(last-first) <= 1e-3*math.Abs(first) {
+		issues = append(issues, Stagnation)
+	}
+	return issues
+}
@@ -0,0 +1,63 @@
+package rpca
+
+import (
+	"reflect"
+	"testing"
+)
+
+func diagnosticsFromObjectives(converged bool, objectives ...float64) *Diagnostics {
+	d := &Diagnostics{Converged: converged}
+	for i, o := range objectives {
+		d.Iterations = append(d.Iterations, IterationStats{Iteration: i, Objective: o})
+	}
+	return d
+}
+
+func TestIssues(t *testing.T) {
+	tests := []struct {
+		description string
+		diagnostics *Diagnostics
+		expected    []ConvergenceIssue
+	}{
+		{"smooth convergence",
+			diagnosticsFromObjectives(true, 10, 5, 3, 2, 1.5, 1.4),
+			nil},
+		{"oscillation",
+			diagnosticsFromObjectives(true, 10, 5, 8, 4, 7, 3, 6),
+			[]ConvergenceIssue{Oscillation}},
+		{"stagnation",
+			diagnosticsFromObjectives(false, 10, 9.9999, 9.9998, 9.9997, 9.9996),
+			[]ConvergenceIssue{Stagnation}},
+	}
+	for _, test := range tests {
+		observed := test.diagnostics.Issues()
+		if !reflect.DeepEqual(observed, test.expected) {
+			t.Errorf("Failed '%v'. Expected %v but got %v",
+				test.description, test.expected, observed)
+		}
+	}
+}
+
+func TestCollectDiagnostics(t *testing.T) {
+	anoms := FindAnomalies(seasonalSeries(56), AutoDiff(false))
+	if anoms.Diagnostics != nil {
+		t.Errorf("Expected no diagnostics unless asked for")
+	}
+	anoms = FindAnomalies(seasonalSeries(56), AutoDiff(false),
+		CollectDiagnostics(true))
+	if anoms.Diagnostics == nil || len(anoms.Diagnostics.Iterations) == 0 {
+		t.Fatalf("Expected diagnostics to be recorded")
+	}
+	if !anoms.Diagnostics.Converged {
+		t.Errorf("Expected the solver to converge")
+	}
+	for _, stats := range anoms.Diagnostics.Iterations {
+		if stats.Rank < 1 || stats.Rank > 7 {
+			t.Errorf("Rank on iteration %v out of range: %v", stats.Iteration, stats.Rank)
+		}
+		if stats.Sparsity < 0 || stats.Sparsity > 1 {
+			t.Errorf("Sparsity on iteration %v out of range: %v",
+				stats.Iteration, stats.Sparsity)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package rpca
+
+import "math"
+
+/*
+SeasonalESD returns a Detector that runs Seasonal Hybrid ESD (Hochenbaum,
+Vallis and Kejariwal, "Automatic Anomaly Detection in the Cloud Via Statistical
+Learning"), the method behind Twitter's AnomalyDetection package. The median of
+the series is removed first, and then the seasonal component: the median of
+what is left in every slot of the period, as set by the Frequency option. The
+generalized ESD test then looks for up to maxAnomalies (a fraction of the
+series) outliers in what remains, using the median and the median absolute
+deviation in place of the mean and standard deviation so that the anomalies
+themselves do not mask each other. The significance level of the test is one
+minus the Confidence option.
+
+Values hold the residual of every anomalous point, in the domain of the
+series, and NormedValues the residual in units of the scaled median absolute
+deviation. Unlike FindAnomalies, the length of the series need not be
+divisible by the frequency. Other options are ignored.
+*/
+func SeasonalESD(maxAnomalies float64, options ...func(*rpcaConfig) error) Detector {
+	return DetectorFunc(func(series []float64) Anomalies {
+		conf := newConfig(series, options)
+		return seasonalESD(series, conf.frequency, maxAnomalies, 1-conf.confidence)
+	})
+}
+
+func seasonalESD(series []float64, frequency int, maxAnomalies,
+	alpha float64) Anomalies {
+	if frequency <= 0 {
+		panic("Frequency less than or equal to zero")
+	}
+	n := len(series)
+	residuals := seasonalResiduals(series, frequency)
+
+	anomalies := Anomalies{
+		Positions:    make([]bool, n),
+		Values:       make([]float64, n),
+		NormedValues: make([]float64, n),
+	}
+	center, scale := medianAbsoluteDeviation(residuals)
+	if scale == 0 {
+		return anomalies
+	}
+	remaining := make([]bool, n)
+	for i := range remaining {
+		remaining[i] = true
+	}
+	var candidates []int
+	significant := 0
+	for k := 1; k <= int(maxAnomalies*float64(n)) && n-k-1 > 0; k++ {
+		var left []float64
+		for i, r := range residuals {
+			if remaining[i] {
+				left = append(left, r)
+			}
+		}
+		med, mad := medianAbsoluteDeviation(left)
+		if mad == 0 {
+			break
+		}
+		worst, deviation := -1, -1.0
+		for i, r := range residuals {
+			if remaining[i] && math.Abs(r-med) > deviation {
+				worst, deviation = i, math.Abs(r-med)
+			}
+		}
+		p := 1 - alpha/(2*float64(n-k+1))
+		t := studentTQuantile(p, float64(n-k-1))
+		critical := float64(n-k) * t /
+			math.Sqrt((float64(n-k-1)+t*t)*float64(n-k+1))
+		if deviation/mad > critical {
+			significant = k
+		}
+		candidates = append(candidates, worst)
+		remaining[worst] = false
+	}
+	for _, i := range candidates[:significant] {
+		anomalies.Positions[i] = true
+		anomalies.Values[i] = residuals[i] - center
+		anomalies.NormedValues[i] = (residuals[i] - center) / scale
+	}
+	return anomalies
+}
+
+// medianAbsoluteDeviation returns the median of values and their median
+// absolute deviation from it, scaled to estimate the standard deviation of
+// normally distributed values.
+func medianAbsoluteDeviation(values []float64) (float64, float64) {
+	med := median(append([]float64(nil), values...))
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - med)
+	}
+	return med, 1.4826 * median(deviations)
+}
+
+// studentTQuantile returns the p-th quantile of Student's t distribution with
+// df degrees of freedom, using the Cornish-Fisher expansion around the normal
+// quantile. It is accurate to about three decimal places from five degrees of
+// freedom up.
+func studentTQuantile(p, df float64) float64 {
+	z := math.Sqrt2 * math.Erfinv(2*p-1)
+	z2 := z * z
+	return z +
+		z*(z2+1)/(4*df) +
+		z*(5*z2*z2+16*z2+3)/(96*df*df) +
+		z*(3*z2*z2*z2+19*z2*z2+17*z2-15)/(384*df*df*df) +
+		z*(79*z2*z2*z2*z2+776*z2*z2*z2+1482*z2*z2-1920*z2-945)/(92160*df*df*df*df)
+}
+
+// seasonalResiduals removes the median of the series, and then the median of
+// what is left in every slot of the period, from every point.
+func seasonalResiduals(series []float64, frequency int) []float64 {
+	level := median(append([]float64(nil), series...))
+	slots := make([][]float64, frequency)
+	for i, v := range series {
+		slots[i%frequency] = append(slots[i%frequency], v-level)
+	}
+	seasonal := make([]float64, frequency)
+	for slot, values := range slots {
+		if len(values) > 0 {
+			seasonal[slot] = median(values)
+		}
+	}
+	residuals := make([]float64, len(series))
+	for i, v := range series {
+		residuals[i] = v - level - seasonal[i%frequency]
+	}
+	return residuals
+}
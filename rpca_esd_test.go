@@ -0,0 +1,53 @@
+package rpca
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStudentTQuantile(t *testing.T) {
+	tests := []struct{ p, df, expected float64 }{
+		{0.975, 10, 2.228},
+		{0.975, 30, 2.042},
+		{0.995, 20, 2.845},
+		{0.5, 7, 0},
+	}
+	for _, test := range tests {
+		if observed := studentTQuantile(test.p, test.df); math.Abs(observed-test.expected) > 0.002 {
+			t.Errorf("Failed t quantile %v with %v degrees of freedom. Expected "+
+				"%v but got %v", test.p, test.df, test.expected, observed)
+		}
+	}
+}
+
+func TestSeasonalESD(t *testing.T) {
+	series := seasonalSeries(60)
+	series[20] += 5
+	series[41] -= 8
+	anoms := SeasonalESD(0.1).Detect(series)
+	for i, p := range anoms.Positions {
+		if p != (i == 20 || i == 41) {
+			t.Errorf("Failed point %v. Expected anomalous to be %v but got %v",
+				i, !p, p)
+		}
+	}
+	if math.Abs(anoms.Values[41]+8) > 0.5 || anoms.NormedValues[41] >= 0 {
+		t.Errorf("Expected a dip of about 8 at 41, but got %v", anoms.Values[41])
+	}
+}
+
+func TestSeasonalResidualsWithLevel(t *testing.T) {
+	series := seasonalSeries(56)
+	for i := range series {
+		series[i] += 1e6
+	}
+	series[30] += 20
+	residuals := seasonalResiduals(series, 7)
+	if center := median(append([]float64(nil), residuals...)); math.Abs(center) > 1 {
+		t.Errorf("Expected residuals centered on zero but got a median of %v",
+			center)
+	}
+	if math.Abs(residuals[30]-20) > 1 {
+		t.Errorf("Expected a residual of about 20 at 30 but got %v", residuals[30])
+	}
+}
@@ -0,0 +1,93 @@
+package rpca
+
+import "time"
+
+// exclusion is a range of points masked from detection, either by index or,
+// if byTime is set, by timestamp. Both ranges include their start and exclude
+// their end.
+type exclusion struct {
+	start, end int
+	from, to   time.Time
+	byTime     bool
+}
+
+// excluded returns which of the n points of the series are masked by the
+// configured exclusions and masked holidays, or nil if there are none.
+func (conf *rpcaConfig) excluded(n int) []bool {
+	masksHolidays := conf.calendar != nil && conf.holidayMode == MaskHolidays
+	if len(conf.exclusions) == 0 && !masksHolidays {
+		return nil
+	}
+	mask := make([]bool, n)
+	if masksHolidays {
+		copy(mask, conf.holidays(n))
+	}
+	for _, ex := range conf.exclusions {
+		if !ex.byTime {
+			for i := max(ex.start, 0); i < min(ex.end, n); i++ {
+				mask[i] = true
+			}
+			continue
+		}
+		conf.checkTimestamps(n)
+		for i, t := range conf.timestamps {
+			if !t.Before(ex.from) && t.Before(ex.to) {
+				mask[i] = true
+			}
+		}
+	}
+	return mask
+}
+
+// checkTimestamps panics unless there is a timestamp for each of the n points
+// of the series.
+func (conf *rpcaConfig) checkTimestamps(n int) {
+	if len(conf.timestamps) != n {
+		panic("Number of timestamps not equal to length of time series")
+	}
+}
+
+// holidays returns which of the n points of the series fall on a holiday of
+// the configured calendar.
+func (conf *rpcaConfig) holidays(n int) []bool {
+	conf.checkTimestamps(n)
+	return conf.calendar.on(conf.timestamps)
+}
+
+// pointWeights combines the configured weights and exclusions into one weight
+// per point of the series, or returns nil if neither is configured.
+func (conf *rpcaConfig) pointWeights(n int) []float64 {
+	mask := conf.excluded(n)
+	if conf.weights == nil && mask == nil {
+		return nil
+	}
+	var weights []float64
+	if conf.weights != nil {
+		weights = normalizeWeights(conf.weights, n)
+	} else {
+		weights = make([]float64, n)
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+	for i, masked := range mask {
+		if masked {
+			weights[i] = 0
+		}
+	}
+	return weights
+}
+
+// expectedDeviations returns how far every excluded point was from the
+// low-rank fit. Excluded points are never thresholded into the sparse
+// component, so that is their noise component.
+func expectedDeviations(decomp *decomposedMatrix, excluded []bool) []float64 {
+	e := decomp.unfold(decomp.E)
+	deviations := make([]float64, len(e))
+	for i, masked := range excluded {
+		if masked {
+			deviations[i] = e[i]
+		}
+	}
+	return deviations
+}
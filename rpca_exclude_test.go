@@ -0,0 +1,113 @@
+package rpca
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestExclude(t *testing.T) {
+	series := seasonalSeries(56)
+	series[30] += 50
+	series[40] += 50
+	start := time.Date(2016, time.January, 4, 0, 0, 0, 0, time.UTC)
+	timestamps := make([]time.Time, len(series))
+	for i := range timestamps {
+		timestamps[i] = start.AddDate(0, 0, i)
+	}
+	tests := []struct {
+		description string
+		option      func(*rpcaConfig) error
+	}{
+		{"by index", Exclude(29, 32)},
+		{"by time", ExcludeTimes(timestamps[29], timestamps[32])},
+	}
+	for _, test := range tests {
+		anoms := FindAnomalies(series, AutoDiff(false), Timestamps(timestamps),
+			test.option)
+		for i, excluded := range anoms.Excluded {
+			if excluded != (i >= 29 && i < 32) {
+				t.Errorf("Failed '%v' on point %v. Expected excluded to be %v but "+
+					"got %v", test.description, i, !excluded, excluded)
+			}
+		}
+		if anoms.Positions[30] {
+			t.Errorf("Failed '%v'. Expected excluded spike not to be flagged",
+				test.description)
+		}
+		if !anoms.Positions[40] {
+			t.Errorf("Failed '%v'. Expected spike at 40 to be flagged",
+				test.description)
+		}
+		if math.Abs(anoms.ExpectedDeviations[30]-50) > 2 {
+			t.Errorf("Failed '%v'. Expected a deviation of about 50 at 30, but "+
+				"got %v", test.description, anoms.ExpectedDeviations[30])
+		}
+		if anoms.ExpectedDeviations[40] != 0 {
+			t.Errorf("Failed '%v'. Expected no deviation reported outside the "+
+				"exclusion, but got %v", test.description, anoms.ExpectedDeviations[40])
+		}
+	}
+}
+
+func TestSurusOnlyOptions(t *testing.T) {
+	start := time.Date(2016, time.January, 4, 0, 0, 0, 0, time.UTC)
+	timestamps := make([]time.Time, 56)
+	for i := range timestamps {
+		timestamps[i] = start.AddDate(0, 0, i)
+	}
+	weights := make([]float64, len(timestamps))
+	for i := range weights {
+		weights[i] = 1
+	}
+	options := map[string]func(*rpcaConfig) error{
+		"Weights":    Weights(weights),
+		"Exclude":    Exclude(29, 32),
+		"Holidays":   Holidays(&Calendar{}, HolidayPattern),
+		"Monthly":    Monthly(true),
+		"NoiseModel": NoiseModel(Huber(1)),
+	}
+	for name, option := range options {
+		for _, solver := range []Algorithm{InexactALM, AltProj, GoDec} {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("Expected %v with solver %v to panic", name, solver)
+					}
+				}()
+				FindAnomalies(seasonalSeries(56), AutoDiff(false),
+					Timestamps(timestamps), Solver(solver), option)
+			}()
+		}
+	}
+}
+
+func TestExcludedNoise(t *testing.T) {
+	series := seasonalSeries(56)
+	series[40] += 5
+	spiked := append([]float64(nil), series...)
+	spiked[30] += 50
+	options := []func(*rpcaConfig) error{
+		AutoDiff(false), PValues(true), Exclude(30, 31),
+	}
+
+	width := func(prediction Prediction) float64 {
+		return prediction.Upper[0] - prediction.Lower[0]
+	}
+	clean, excluded := Forecast(series, 7, options...), Forecast(spiked, 7, options...)
+	if math.Abs(width(excluded)-width(clean)) > 0.1*width(clean) {
+		t.Errorf("Expected the excluded spike not to widen the prediction "+
+			"interval of %v, but got %v", width(clean), width(excluded))
+	}
+
+	cleanAnoms := FindAnomalies(series, options...)
+	anoms := FindAnomalies(spiked, options...)
+	if anoms.PValues[30] != 1 {
+		t.Errorf("Expected the excluded point to have a p-value of 1 but got %v",
+			anoms.PValues[30])
+	}
+	if anoms.PValues[40] > 2*cleanAnoms.PValues[40] {
+		t.Errorf("Expected the excluded spike not to raise the p-value %v of the "+
+			"spike at 40, but got %v", cleanAnoms.PValues[40], anoms.PValues[40])
+	}
+}
@@ -0,0 +1,366 @@
+package rpca
+
+import (
+	"math"
+	"time"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+/*
+folding maps every point of a time series to a cell of the matrix that is
+decomposed. The regular folding puts point i in row i % frequency of column
+i / frequency, just like buildMatrix. Calendar-aware options fold differently,
+and may leave cells that no point maps to. Such cells are missing: they are
+filled in with the typical value of their row and fit as if they were
+observed, unless they are masked, in which case they carry no weight and
+neither count towards the low-rank fit nor get flagged. Filling keeps a row or
+column that is mostly missing from being shrunk far more by the L penalty than
+those that are fully observed, which would leave its few points flagged.
+Several points may also share a cell, which then holds their mean.
+*/
+type folding struct {
+	rows, cols int
+
+	// The index of the cell every point is folded into, counting down each
+	// column in turn.
+	cells []int
+
+	// Which cells, counting down each column in turn, are masked, or nil if
+	// none are.
+	masked []bool
+}
+
+// regularFolding folds n points frequency at a time, starting with the given
+// phase: the slot of the period the first point falls in. Unless the phase is
+// zero, the first and last columns may be partly missing.
+func regularFolding(n, frequency, phase int) *folding {
+	if frequency <= 0 {
+		panic("Frequency less than or equal to zero")
+	}
+	if phase == 0 && n%frequency != 0 {
+		panic("Time series not evenly divisible by frequency")
+	}
+	cells := make([]int, n)
+	for i := range cells {
+		cells[i] = i + phase
+	}
+	cols := (n + phase + frequency - 1) / frequency
+	return &folding{rows: frequency, cols: cols, cells: cells}
+}
+
+/*
+wallClockFolding folds points by the wall-clock time of their timestamps in
+loc, so that every row holds the same local time of day (or of week) even
+across daylight saving time transitions. The sampling interval is the most
+common wall-clock difference between consecutive timestamps. Point i goes in
+the slot the given number of intervals after the first point's, rounded to the
+nearest, and the first point goes in the slot given by the phase; slots then
+fill each column in turn, frequency at a time.
+
+On a day with a skipped hour, the slot for that hour is missing. On a day with
+a repeated hour, both points share a slot. Gaps in the series are also
+missing, so the timestamps need not be evenly spaced.
+*/
+func wallClockFolding(timestamps []time.Time, frequency, phase int,
+	loc *time.Location) *folding {
+	if frequency <= 0 {
+		panic("Frequency less than or equal to zero")
+	}
+	walls := wallClocks(timestamps, loc)
+	interval := commonInterval(walls)
+	cells := make([]int, len(walls))
+	slots := 0
+	for i, w := range walls {
+		cells[i] = phase + intervals(w.Sub(walls[0]), interval)
+		slots = max(slots, cells[i]+1)
+	}
+	cols := (slots + frequency - 1) / frequency
+	return &folding{rows: frequency, cols: cols, cells: cells}
+}
+
+// monthFolding folds points by the day of the month of their timestamps in
+// loc, or in their own location if loc is nil, with one column per calendar
+// month starting from the first point's. Days past the end of shorter months
+// are masked. Days in the first and last months that the series does not
+// cover are missing, like gaps.
+func monthFolding(timestamps []time.Time, loc *time.Location) *folding {
+	const rows = 31
+	if len(timestamps) == 0 {
+		panic("Time series empty")
+	}
+	local := func(t time.Time) time.Time {
+		if loc != nil {
+			return t.In(loc)
+		}
+		return t
+	}
+	first := local(timestamps[0])
+	cells := make([]int, len(timestamps))
+	cols := 0
+	for i, t := range timestamps {
+		y, m, d := local(t).Date()
+		col := (y-first.Year())*12 + int(m-first.Month())
+		cells[i] = col*rows + d - 1
+		cols = max(cols, col+1)
+	}
+	masked := make([]bool, rows*cols)
+	for col := 0; col < cols; col++ {
+		// Day zero of the following month is the last day of this one.
+		days := time.Date(first.Year(), first.Month()+time.Month(col)+1, 0, 0, 0, 0, 0,
+			time.UTC).Day()
+		for d := days; d < rows; d++ {
+			masked[col*rows+d] = true
+		}
+	}
+	return &folding{rows: rows, cols: cols, cells: cells, masked: masked}
+}
+
+// wallClocks returns the wall-clock time of each timestamp in loc, as a time
+// in UTC so that differences between them are in wall-clock time.
+func wallClocks(timestamps []time.Time, loc *time.Location) []time.Time {
+	walls := make([]time.Time, len(timestamps))
+	for i, t := range timestamps {
+		y, m, d := t.In(loc).Date()
+		h, min, sec := t.In(loc).Clock()
+		walls[i] = time.Date(y, m, d, h, min, sec, t.Nanosecond(), time.UTC)
+	}
+	return walls
+}
+
+// intervals returns how many intervals fit in d, rounded to the nearest.
+func intervals(d, interval time.Duration) int {
+	return int(math.Round(float64(d) / float64(interval)))
+}
+
+// commonInterval returns the most common positive difference between
+// consecutive times, preferring the shortest on ties.
+func commonInterval(times []time.Time) time.Duration {
+	counts := make(map[time.Duration]int)
+	var interval time.Duration
+	for i := 1; i < len(times); i++ {
+		d := times[i].Sub(times[i-1])
+		if d <= 0 {
+			continue
+		}
+		counts[d]++
+		if interval == 0 || counts[d] > counts[interval] ||
+			(counts[d] == counts[interval] && d < interval) {
+			interval = d
+		}
+	}
+	if interval == 0 {
+		panic("Timestamps not increasing")
+	}
+	return interval
+}
+
+// folding returns how to fold a series of n points, as configured.
+func (conf *rpcaConfig) folding(n int) *folding {
+	var fold *folding
+	if conf.monthly {
+		conf.checkTimestamps(n)
+		fold = monthFolding(conf.timestamps, conf.location)
+	} else if conf.location != nil {
+		conf.checkTimestamps(n)
+		fold = wallClockFolding(conf.timestamps, conf.frequency, conf.phase(n),
+			conf.location)
+	} else {
+		fold = regularFolding(n, conf.frequency, conf.phase(n))
+	}
+	if conf.calendar != nil && conf.holidayMode == HolidayPattern {
+		fold = fold.separate(conf.holidays(n))
+	}
+	return fold
+}
+
+// phase returns the slot of the period that the first of the n points of the
+// series falls in, as set by the PhaseOffset or SeasonStart options.
+func (conf *rpcaConfig) phase(n int) int {
+	phase := conf.phaseOffset
+	if !conf.seasonStart.IsZero() {
+		conf.checkTimestamps(n)
+		timestamps := append([]time.Time{conf.seasonStart}, conf.timestamps...)
+		if conf.location != nil {
+			timestamps = wallClocks(timestamps, conf.location)
+		}
+		phase = intervals(timestamps[1].Sub(timestamps[0]),
+			commonInterval(timestamps[1:]))
+	}
+	if conf.frequency <= 0 {
+		return phase
+	}
+	return (phase%conf.frequency + conf.frequency) % conf.frequency
+}
+
+// separate moves the given points out of their cells and into extra rows at
+// the bottom of their columns, in order, leaving their cells masked. The column
+// with the most such points sets how many rows are added. Cells of the extra
+// rows that no point is moved into are filled, so each row holds the typical
+// value of the points in it.
+func (f *folding) separate(points []bool) *folding {
+	counts := make([]int, f.cols)
+	extra := 0
+	for i, p := range points {
+		if p {
+			col := f.cells[i] / f.rows
+			counts[col]++
+			extra = max(extra, counts[col])
+		}
+	}
+	if extra == 0 {
+		return f
+	}
+	rows := f.rows + extra
+	cells := make([]int, len(f.cells))
+	masked := make([]bool, rows*f.cols)
+	for c, m := range f.masked {
+		masked[c/f.rows*rows+c%f.rows] = m
+	}
+	counts = make([]int, f.cols)
+	for i, c := range f.cells {
+		row, col := c%f.rows, c/f.rows
+		if points[i] {
+			masked[col*rows+row] = true
+			row = f.rows + counts[col]
+			counts[col]++
+		}
+		cells[i] = col*rows + row
+	}
+	// A cell keeps points that are not moved, such as the other point of a
+	// repeated hour.
+	for _, c := range cells {
+		masked[c] = false
+	}
+	return &folding{rows: rows, cols: f.cols, cells: cells, masked: masked}
+}
+
+// missing returns which cells, counting down each column in turn, no point is
+// folded into, or nil if there are none.
+func (f *folding) missing() []bool {
+	missing := make([]bool, f.rows*f.cols)
+	for i := range missing {
+		missing[i] = true
+	}
+	for _, c := range f.cells {
+		missing[c] = false
+	}
+	for _, m := range missing {
+		if m {
+			return missing
+		}
+	}
+	return nil
+}
+
+// fold builds the matrix for a series. Missing cells are filled with the median
+// of the rest of their row, which keeps masked cells from skewing scaling, and
+// keeps filled cells from taking after the anomalies in their row.
+func (f *folding) fold(series []float64) rPCAable {
+	if len(series) != len(f.cells) {
+		panic("Time series not the length of its folding")
+	}
+	data := f.mean(series)
+	if missing := f.missing(); missing != nil {
+		present := make([][]float64, f.rows)
+		for c, m := range missing {
+			if !m {
+				present[c%f.rows] = append(present[c%f.rows], data[c])
+			}
+		}
+		for c, m := range missing {
+			if m && len(present[c%f.rows]) > 0 {
+				data[c] = median(present[c%f.rows])
+			}
+		}
+	}
+	return buildMatrix(data, f.rows)
+}
+
+// unfold maps the cells of a matrix, counting down each column in turn, back
+// to the points of the series.
+func (f *folding) unfold(cells []float64) []float64 {
+	series := make([]float64, len(f.cells))
+	for i, c := range f.cells {
+		series[i] = cells[c]
+	}
+	return series
+}
+
+// foldWeights builds the weight matrix for the given point weights, which may
+// be nil if all points are weighted equally. Filled cells get a weight of one
+// and masked cells a weight of zero. It returns nil if every cell would have a
+// weight of one.
+func (f *folding) foldWeights(weights []float64) *mat64.Dense {
+	if weights == nil {
+		weights = make([]float64, len(f.cells))
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+	data := f.mean(weights)
+	unweighted := true
+	missing := f.missing()
+	for c := range data {
+		if missing != nil && missing[c] && (f.masked == nil || !f.masked[c]) {
+			data[c] = 1
+		}
+		unweighted = unweighted && data[c] == 1
+	}
+	if unweighted {
+		return nil
+	}
+	return buildMatrix(data, f.rows).(*mat64.Dense)
+}
+
+// mean returns, for every cell, the mean of the values of the points folded
+// into it, or zero if there are none.
+func (f *folding) mean(values []float64) []float64 {
+	data := make([]float64, f.rows*f.cols)
+	counts := make([]float64, f.rows*f.cols)
+	for i, c := range f.cells {
+		data[c] += values[i]
+		counts[c]++
+	}
+	for c, n := range counts {
+		if n > 1 {
+			data[c] /= n
+		}
+	}
+	return data
+}
+
+// period returns the number of points in the longest column, which is the
+// length of a full period: the frequency, or 31 days when folding by month.
+func (f *folding) period() int {
+	counts := make([]int, f.cols)
+	longest := 0
+	for _, c := range f.cells {
+		counts[c/f.rows]++
+		longest = max(longest, counts[c/f.rows])
+	}
+	return longest
+}
+
+// lastInRow returns the value of the last point before point end that was
+// folded into the given row, or false if there is none.
+func (f *folding) lastInRow(values []float64, row, end int) (float64, bool) {
+	for i := end - 1; i >= 0; i-- {
+		if f.cells[i]%f.rows == row {
+			return values[i], true
+		}
+	}
+	return 0, false
+}
+
+// periods returns, for every column, whether any of the points folded into it
+// were anomalous.
+func (f *folding) periods(positions []bool) []bool {
+	periods := make([]bool, f.cols)
+	for i, p := range positions {
+		if p {
+			periods[f.cells[i]/f.rows] = true
+		}
+	}
+	return periods
+}
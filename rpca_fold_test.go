@@ -0,0 +1,192 @@
+package rpca
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func newYork(t *testing.T) *time.Location {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("Time zone database not available: %v", err)
+	}
+	return loc
+}
+
+// hourlyTimestamps returns n hourly timestamps starting at local midnight on
+// the given day.
+func hourlyTimestamps(n int, year int, month time.Month, day int,
+	loc *time.Location) []time.Time {
+	start := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	timestamps := make([]time.Time, n)
+	for i := range timestamps {
+		timestamps[i] = start.Add(time.Duration(i) * time.Hour)
+	}
+	return timestamps
+}
+
+func TestWallClockFolding(t *testing.T) {
+	loc := newYork(t)
+
+	// March 13, 2016 has 23 hours, so 48 hourly points cover 49 slots.
+	spring := wallClockFolding(hourlyTimestamps(48, 2016, time.March, 13, loc), 24, 0, loc)
+	if spring.rows != 24 || spring.cols != 3 {
+		t.Errorf("Expected a 24x3 folding in spring but got %vx%v", spring.rows,
+			spring.cols)
+	}
+	if spring.cells[1] != 1 || spring.cells[2] != 3 || spring.cells[47] != 48 {
+		t.Errorf("Expected the slot for 2AM to be skipped, but got cells %v",
+			spring.cells[:4])
+	}
+	if missing := spring.missing(); !missing[2] || missing[3] {
+		t.Errorf("Expected only the skipped hour to be missing on the first day")
+	}
+
+	// November 6, 2016 has 25 hours, so 1AM is repeated.
+	autumn := wallClockFolding(hourlyTimestamps(49, 2016, time.November, 6, loc), 24, 0, loc)
+	if autumn.cols != 2 || autumn.cells[1] != 1 || autumn.cells[2] != 1 ||
+		autumn.cells[48] != 47 {
+		t.Errorf("Expected both 1AM points to share a slot, but got cells %v",
+			autumn.cells[:4])
+	}
+	values := make([]float64, 49)
+	values[1], values[2] = 2, 4
+	if v := autumn.fold(values).At(1, 0); v != 3 {
+		t.Errorf("Expected the repeated hour to hold the mean 3, but got %v", v)
+	}
+}
+
+func TestLocation(t *testing.T) {
+	loc := newYork(t)
+	timestamps := hourlyTimestamps(24*14, 2016, time.February, 29, loc)
+	series := make([]float64, len(timestamps))
+	for i, ts := range timestamps {
+		// Busy during local business hours.
+		series[i] = 5 + 0.1*math.Cos(float64(i*i))
+		if ts.Hour() >= 9 && ts.Hour() < 17 {
+			series[i] += 15
+		}
+	}
+	series[200] += 30
+
+	flagged := func(anoms Anomalies) []int {
+		var positions []int
+		for i, v := range anoms.Values {
+			if math.Abs(v) > 1 {
+				positions = append(positions, i)
+			}
+		}
+		return positions
+	}
+	counted := flagged(FindAnomalies(series, Frequency(24), AutoDiff(false)))
+	if len(counted) <= 1 {
+		t.Errorf("Expected the DST transition to cause spurious anomalies when "+
+			"folding by count, but got %v", counted)
+	}
+	local := flagged(FindAnomalies(series, Frequency(24), AutoDiff(false),
+		Timestamps(timestamps), Location(loc)))
+	if len(local) != 1 || local[0] != 200 {
+		t.Errorf("Expected only the spike at 200 to be flagged when folding by "+
+			"wall-clock time, but got %v", local)
+	}
+}
+
+func TestMonthFolding(t *testing.T) {
+	start := time.Date(2016, time.January, 15, 0, 0, 0, 0, time.UTC)
+	timestamps := make([]time.Time, 60)
+	for i := range timestamps {
+		timestamps[i] = start.AddDate(0, 0, i)
+	}
+	fold := monthFolding(timestamps, nil)
+	if fold.rows != 31 || fold.cols != 3 {
+		t.Fatalf("Expected a 31x3 folding but got %vx%v", fold.rows, fold.cols)
+	}
+	// February 1 is the 17th point, and March 14 the last.
+	if fold.cells[0] != 14 || fold.cells[17] != 31 || fold.cells[59] != 62+13 {
+		t.Errorf("Expected points folded by day of month, but got cells %v, %v "+
+			"and %v", fold.cells[0], fold.cells[17], fold.cells[59])
+	}
+	for d := 0; d < 31; d++ {
+		if fold.masked[31+d] != (d >= 29) {
+			t.Errorf("Expected only days past February 29 to be masked, but day "+
+				"%v was masked: %v", d+1, fold.masked[31+d])
+		}
+	}
+	weights := fold.foldWeights(nil)
+	if weights.At(0, 0) != 1 || weights.At(30, 1) != 0 {
+		t.Errorf("Expected days before the series to be filled and days past the " +
+			"end of February to be masked")
+	}
+	if fold.period() != 29 {
+		t.Errorf("Expected the longest period to be February's 29 days but got %v",
+			fold.period())
+	}
+}
+
+func TestMonthly(t *testing.T) {
+	start := time.Date(2016, time.January, 1, 0, 0, 0, 0, time.UTC)
+	timestamps := make([]time.Time, 728)
+	series := make([]float64, len(timestamps))
+	for i := range timestamps {
+		timestamps[i] = start.AddDate(0, 0, i)
+		series[i] = 10 + 0.1*math.Cos(float64(i*i))
+		// Billing runs on the first of every month.
+		if timestamps[i].Day() == 1 {
+			series[i] += 20
+		}
+	}
+	series[200] += 20
+
+	weekly := FindAnomalies(series, AutoDiff(false))
+	if !weekly.Positions[31] {
+		t.Errorf("Expected billing days to be flagged when folding by week")
+	}
+	monthly := FindAnomalies(series, AutoDiff(false), Timestamps(timestamps),
+		Monthly(true))
+	for i, p := range monthly.Positions {
+		if p != (i == 200) && math.Abs(monthly.Values[i]) > 1 {
+			t.Errorf("Expected only the spike at 200 to be flagged when folding "+
+				"by month, but %v was flagged with %v", i, monthly.Values[i])
+		}
+	}
+	if !monthly.Positions[200] {
+		t.Errorf("Expected spike at 200 to be flagged")
+	}
+}
+
+func TestPhaseOffset(t *testing.T) {
+	// A weekly series that starts on a Wednesday, two days into the week.
+	series := seasonalSeries(58)[2:]
+	series[30] += 20
+	start := time.Date(2016, time.January, 6, 0, 0, 0, 0, time.UTC)
+	timestamps := make([]time.Time, len(series))
+	for i := range timestamps {
+		timestamps[i] = start.AddDate(0, 0, i)
+	}
+	monday := time.Date(2015, time.December, 28, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		description string
+		options     []func(*rpcaConfig) error
+	}{
+		{"offset", []func(*rpcaConfig) error{PhaseOffset(2)}},
+		{"season start", []func(*rpcaConfig) error{Timestamps(timestamps),
+			SeasonStart(monday)}},
+	}
+	for _, test := range tests {
+		conf := newConfig(series, test.options)
+		fold := conf.folding(len(series))
+		if fold.cols != 9 || fold.cells[0] != 2 || fold.cells[5] != 7 {
+			t.Errorf("Failed '%v'. Expected the first point in slot 2 of 9 "+
+				"columns, but got slot %v of %v", test.description, fold.cells[0],
+				fold.cols)
+		}
+		anoms := FindAnomalies(series, append(test.options, AutoDiff(false))...)
+		for i, v := range anoms.Values {
+			if (math.Abs(v) > 1) != (i == 30) {
+				t.Errorf("Failed '%v'. Expected only the spike at 30 to be "+
+					"flagged, but %v had a value of %v", test.description, i, v)
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package rpca
+
+import (
+	"math"
+
+	"github.com/gonum/stat"
+)
+
+type Prediction struct {
+	// Values holds the expected value of each of the forecasted points, in the
+	// domain of the given time series.
+	Values []float64
+
+	// Lower and Upper bound the prediction interval around each forecasted
+	// point. The width of the interval is controlled by the Confidence option.
+	Lower, Upper []float64
+}
+
+/*
+Forecast projects the given time series horizon points into the future using
+the same decomposition that FindAnomalies uses to detect anomalies. The seasonal
+profile learned by the low-rank component (L) for the most recent period is
+repeated forward, so a horizon of 2*frequency forecasts two full periods. With
+the Monthly option, every forecasted day takes the profile of the same day of
+the month in the most recent month that has it. If the series was differenced,
+the profile describes the change from each point to the next, and the forecast
+adds those changes up from the last observed point, carrying the trend forward.
+
+Prediction intervals are derived from the spread of the noise component (E).
+When the series was differenced, the noise accumulates and the intervals widen
+with the square root of the distance from the last observed point.
+
+Forecast takes the same options as FindAnomalies. For example:
+
+	prediction := rpca.Forecast(series, 14, rpca.Frequency(7), rpca.Confidence(0.9))
+*/
+func Forecast(series []float64, horizon int, options ...func(*rpcaConfig) error) Prediction {
+	if horizon < 0 {
+		panic("Forecast horizon less than zero")
+	}
+	conf := newConfig(series, options)
+	transformed := conf.transform(series)
+	decomposed := decompose(transformed, &conf)
+
+	steps := conf.seasonalSteps(decomposed.fold, decomposed.unfold(decomposed.L),
+		horizon)
+	noise := stat.StdDev(decomposed.weightedPoints(decomposed.unfold(decomposed.E)),
+		nil)
+	z := math.Sqrt2 * math.Erfinv(conf.confidence)
+
+	prediction := Prediction{
+		Values: make([]float64, horizon),
+		Lower:  make([]float64, horizon),
+		Upper:  make([]float64, horizon),
+	}
+	level := transformed[len(transformed)-1]
+	for h := 0; h < horizon; h++ {
+		step := steps[h]
+		width := z * noise
+		if decomposed.differenced {
+			level += step
+			step = level
+			width *= math.Sqrt(float64(h + 1))
+		}
+		prediction.Values[h] = conf.untransform(step)
+		prediction.Lower[h] = conf.untransform(step - width)
+		prediction.Upper[h] = conf.untransform(step + width)
+	}
+	return prediction
+}
+
+// seasonalSteps returns the fitted value that the profile gives each of the
+// horizon points after the series: that of the point a period before it, where
+// a period is frequency points, or a calendar month with the Monthly option.
+func (conf *rpcaConfig) seasonalSteps(fold *folding, fitted []float64,
+	horizon int) []float64 {
+	steps := make([]float64, horizon)
+	if !conf.monthly {
+		profile := fitted[len(fitted)-conf.frequency:]
+		for h := range steps {
+			steps[h] = profile[h%len(profile)]
+		}
+		return steps
+	}
+	last := conf.timestamps[len(conf.timestamps)-1]
+	if conf.location != nil {
+		last = last.In(conf.location)
+	}
+	for h := range steps {
+		// Rows hold the days of the month, so a day missing from the last
+		// month, such as the 31st, comes from the month before.
+		row := last.AddDate(0, 0, h+1).Day() - 1
+		steps[h], _ = fold.lastInRow(fitted, row, len(fitted))
+	}
+	return steps
+}
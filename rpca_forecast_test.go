@@ -0,0 +1,103 @@
+package rpca
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func seasonalSeries(n int) []float64 {
+	series := make([]float64, n)
+	for i := range series {
+		series[i] = 10 + 5*math.Sin(2*math.Pi*float64(i)/7) + 0.1*math.Cos(float64(i*i))
+	}
+	return series
+}
+
+func TestForecast(t *testing.T) {
+	series := seasonalSeries(56)
+	prediction := Forecast(series, 14, AutoDiff(false))
+	if len(prediction.Values) != 14 {
+		t.Fatalf("Expected 14 forecasted points but got %v", len(prediction.Values))
+	}
+	for h, v := range prediction.Values {
+		expected := 10 + 5*math.Sin(2*math.Pi*float64(56+h)/7)
+		if math.Abs(v-expected) > 0.5 {
+			t.Errorf("Failed forecasting point %v. Expected about %v but got %v",
+				h, expected, v)
+		}
+		if prediction.Lower[h] >= v || prediction.Upper[h] <= v {
+			t.Errorf("Expected %v to be inside its prediction interval [%v, %v]",
+				v, prediction.Lower[h], prediction.Upper[h])
+		}
+	}
+}
+
+func TestForecastDifferenced(t *testing.T) {
+	series := seasonalSeries(56)
+	for i := range series {
+		series[i] += 2 * float64(i)
+	}
+	prediction := Forecast(series, 14, AutoDiff(false), ForceDiff(true))
+	for h, v := range prediction.Values {
+		expected := 10 + 5*math.Sin(2*math.Pi*float64(56+h)/7) + 2*float64(56+h)
+		if math.Abs(v-expected) > 2 {
+			t.Errorf("Failed forecasting point %v. Expected about %v but got %v",
+				h, expected, v)
+		}
+	}
+	first := prediction.Upper[0] - prediction.Lower[0]
+	last := prediction.Upper[13] - prediction.Lower[13]
+	if last <= first {
+		t.Errorf("Expected prediction interval to widen, but went from %v to %v",
+			first, last)
+	}
+}
+
+func TestForecastDifferencedSteps(t *testing.T) {
+	// Differencing a seasonal series without a trend leaves the changes from
+	// each point to the next, which add back up to the seasonal pattern
+	// without drifting.
+	series := seasonalSeries(56)
+	prediction := Forecast(series, 14, AutoDiff(false), ForceDiff(true))
+	previous := series[len(series)-1]
+	for h, v := range prediction.Values {
+		expected := 10 + 5*math.Sin(2*math.Pi*float64(56+h)/7)
+		if math.Abs(v-expected) > 0.5 {
+			t.Errorf("Failed forecasting point %v. Expected about %v but got %v",
+				h, expected, v)
+		}
+		change := expected - (10 + 5*math.Sin(2*math.Pi*float64(55+h)/7))
+		if math.Abs(v-previous-change) > 0.5 {
+			t.Errorf("Failed forecasting the change to point %v. Expected about %v "+
+				"but got %v", h, change, v-previous)
+		}
+		previous = v
+	}
+}
+
+func TestMonthlyForecast(t *testing.T) {
+	start := time.Date(2016, time.January, 1, 0, 0, 0, 0, time.UTC)
+	timestamps := make([]time.Time, 728)
+	series := make([]float64, len(timestamps))
+	for i := range timestamps {
+		timestamps[i] = start.AddDate(0, 0, i)
+		series[i] = 10 + 0.1*math.Cos(float64(i*i))
+		if timestamps[i].Day() == 1 {
+			series[i] += 20
+		}
+	}
+	last := timestamps[len(timestamps)-1]
+	prediction := Forecast(series, 62, AutoDiff(false), Timestamps(timestamps),
+		Monthly(true))
+	for h, v := range prediction.Values {
+		expected := 10.0
+		if last.AddDate(0, 0, h+1).Day() == 1 {
+			expected = 30
+		}
+		if math.Abs(v-expected) > 2 {
+			t.Errorf("Expected day %v of the forecast to be about %v but got %v",
+				h, expected, v)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package rpca
+
+import (
+	"math"
+	"sort"
+)
+
+// Incident is a group of series whose anomalies overlap in time and move
+// alike, such as every series that depends on a failed service.
+type Incident struct {
+	// Members holds the names of the series in the incident, sorted.
+	Members []string
+
+	// Leader is the member whose anomaly is most similar to those of the
+	// rest, so it is the one to look at first.
+	Leader string
+
+	// Start and End bound the anomalous points of every member: the incident
+	// includes Start and excludes End.
+	Start, End int
+}
+
+// episode is a run of anomalous points in one series.
+type episode struct {
+	series     string
+	start, end int
+	values     []float64
+}
+
+/*
+FindIncidents clusters the anomalies found in many series, keyed by name, into
+incidents, so that a failure seen by hundreds of series raises one alert
+instead of hundreds. Every series' anomalous points are first grouped into
+runs, allowing gaps of up to slack points within a run. Two runs in different
+series belong to the same incident if they overlap or come within slack points
+of each other, and their NormedValues have a cosine similarity of at least
+similarity over the points either covers, so a dip in one series is not
+grouped with a spike in another. Incidents are the connected groups of runs,
+ordered by Start.
+
+All the series must be the same length and aligned in time.
+*/
+func FindIncidents(results map[string]Anomalies, slack int,
+	similarity float64) []Incident {
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var episodes []episode
+	for _, name := range names {
+		episodes = append(episodes, findEpisodes(name, results[name], slack)...)
+	}
+	sort.SliceStable(episodes, func(a, b int) bool {
+		return episodes[a].start < episodes[b].start
+	})
+
+	parents := make([]int, len(episodes))
+	for i := range parents {
+		parents[i] = i
+	}
+	var root func(int) int
+	root = func(i int) int {
+		if parents[i] != i {
+			parents[i] = root(parents[i])
+		}
+		return parents[i]
+	}
+	for a := range episodes {
+		for b := a + 1; b < len(episodes) && episodes[b].start <= episodes[a].end+slack; b++ {
+			if episodes[a].series != episodes[b].series &&
+				shapeSimilarity(episodes[a], episodes[b]) >= similarity {
+				parents[root(b)] = root(a)
+			}
+		}
+	}
+
+	groups := make(map[int][]episode)
+	var roots []int
+	for i, ep := range episodes {
+		r := root(i)
+		if _, ok := groups[r]; !ok {
+			roots = append(roots, r)
+		}
+		groups[r] = append(groups[r], ep)
+	}
+	incidents := make([]Incident, 0, len(roots))
+	for _, r := range roots {
+		incidents = append(incidents, newIncident(groups[r]))
+	}
+	return incidents
+}
+
+// findEpisodes returns the runs of anomalous points in a series, allowing gaps
+// of up to slack points within a run.
+func findEpisodes(name string, anomalies Anomalies, slack int) []episode {
+	var episodes []episode
+	for i, p := range anomalies.Positions {
+		if !p {
+			continue
+		}
+		if n := len(episodes); n > 0 && i-episodes[n-1].end <= slack {
+			episodes[n-1].end = i + 1
+			continue
+		}
+		episodes = append(episodes, episode{series: name, start: i, end: i + 1})
+	}
+	for i := range episodes {
+		episodes[i].values = anomalies.NormedValues[episodes[i].start:episodes[i].end]
+	}
+	return episodes
+}
+
+// shapeSimilarity returns the cosine similarity of the values of two runs over
+// the points either covers, counting points outside a run as zero.
+func shapeSimilarity(a, b episode) float64 {
+	start, end := min(a.start, b.start), max(a.end, b.end)
+	dot, normA, normB := 0.0, 0.0, 0.0
+	for i := start; i < end; i++ {
+		va, vb := a.at(i), b.at(i)
+		dot += va * vb
+		normA += va * va
+		normB += vb * vb
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
+
+func (e episode) at(i int) float64 {
+	if i < e.start || i >= e.end {
+		return 0
+	}
+	return e.values[i-e.start]
+}
+
+// newIncident summarizes a group of runs. The leader is the series of the run
+// with the highest total similarity to the others, or the earliest on ties.
+func newIncident(episodes []episode) Incident {
+	incident := Incident{Start: episodes[0].start, End: episodes[0].end}
+	members := make(map[string]bool)
+	best := math.Inf(-1)
+	for a := range episodes {
+		incident.Start = min(incident.Start, episodes[a].start)
+		incident.End = max(incident.End, episodes[a].end)
+		if !members[episodes[a].series] {
+			members[episodes[a].series] = true
+			incident.Members = append(incident.Members, episodes[a].series)
+		}
+		total := 0.0
+		for b := range episodes {
+			if a != b {
+				total += shapeSimilarity(episodes[a], episodes[b])
+			}
+		}
+		if total > best {
+			best = total
+			incident.Leader = episodes[a].series
+		}
+	}
+	sort.Strings(incident.Members)
+	return incident
+}
@@ -0,0 +1,45 @@
+package rpca
+
+import (
+	"reflect"
+	"testing"
+)
+
+// anomaliesAt returns the anomalies of a series of length n with the given
+// normed values at the given points.
+func anomaliesAt(n int, values map[int]float64) Anomalies {
+	anomalies := Anomalies{
+		Positions:    make([]bool, n),
+		Values:       make([]float64, n),
+		NormedValues: make([]float64, n),
+	}
+	for i, v := range values {
+		anomalies.Positions[i] = true
+		anomalies.Values[i] = 10 * v
+		anomalies.NormedValues[i] = v
+	}
+	return anomalies
+}
+
+func TestFindIncidents(t *testing.T) {
+	results := map[string]Anomalies{
+		// A shared dependency fails at 30, and api is hit hardest.
+		"api":      anomaliesAt(56, map[int]float64{30: -3, 31: -2.5}),
+		"checkout": anomaliesAt(56, map[int]float64{30: -2, 31: -1.5}),
+		"search":   anomaliesAt(56, map[int]float64{31: -2, 32: -1}),
+		// Failover sends traffic elsewhere at the same time.
+		"backup": anomaliesAt(56, map[int]float64{30: 2, 31: 2}),
+		// An unrelated spike later on.
+		"billing": anomaliesAt(56, map[int]float64{30: -1, 45: 4}),
+	}
+	incidents := FindIncidents(results, 1, 0.5)
+	expected := []Incident{
+		{Members: []string{"api", "billing", "checkout", "search"}, Leader: "api",
+			Start: 30, End: 33},
+		{Members: []string{"backup"}, Leader: "backup", Start: 30, End: 32},
+		{Members: []string{"billing"}, Leader: "billing", Start: 45, End: 46},
+	}
+	if !reflect.DeepEqual(incidents, expected) {
+		t.Errorf("Expected incidents %+v but got %+v", expected, incidents)
+	}
+}
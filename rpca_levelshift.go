@@ -0,0 +1,71 @@
+package rpca
+
+import (
+	"math"
+
+	"github.com/gonum/stat"
+)
+
+// LevelShift is a point where a time series settled at a new level.
+type LevelShift struct {
+	// The index of the first point at the new level.
+	Index int
+
+	// The mean of the time series over up to one period before and after the
+	// shift, in the domain of the time series.
+	Before, After float64
+}
+
+/*
+findLevelShifts post-processes the sparse component of a time series to find
+level shifts. How a shift shows up depends on whether the series was
+differenced:
+
+If it was, the sparse component describes anomalous changes from one point to
+the next. A spike is a change that is undone right away by a change of about
+the same size in the other direction, while a shift is a change that is not.
+
+If it was not, the sparse component describes anomalous values. A spike is a
+short run of anomalies, while a shift is a run of anomalies in the same
+direction lasting at least a full period.
+*/
+func findLevelShifts(series, s []float64, differenced bool, frequency int) []LevelShift {
+	var starts []int
+	if differenced {
+		// The first value only pads the differenced series.
+		for i := 1; i < len(s); i++ {
+			if s[i] == 0 {
+				continue
+			}
+			if i+1 < len(s) && s[i]*s[i+1] < 0 &&
+				math.Abs(s[i]+s[i+1]) < 0.5*math.Max(math.Abs(s[i]), math.Abs(s[i+1])) {
+				// Skip the change that undoes this one.
+				i++
+				continue
+			}
+			starts = append(starts, i)
+		}
+	} else {
+		for i := 0; i < len(s); {
+			j := i
+			for j < len(s) && s[j] != 0 && signum(s[j]) == signum(s[i]) {
+				j++
+			}
+			if j-i >= frequency {
+				starts = append(starts, i)
+			}
+			i = int(math.Max(float64(j), float64(i+1)))
+		}
+	}
+
+	shifts := make([]LevelShift, len(starts))
+	for k, i := range starts {
+		before := series[int(math.Max(0, float64(i-frequency))):i]
+		after := series[i:int(math.Min(float64(len(series)), float64(i+frequency)))]
+		shifts[k] = LevelShift{Index: i, After: stat.Mean(after, nil)}
+		if len(before) > 0 {
+			shifts[k].Before = stat.Mean(before, nil)
+		}
+	}
+	return shifts
+}
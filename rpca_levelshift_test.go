@@ -0,0 +1,63 @@
+package rpca
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestFindLevelShifts(t *testing.T) {
+	series := []float64{1, 1, 1, 9, 1, 1, 5, 5, 5, 5}
+	tests := []struct {
+		description string
+		s           []float64
+		differenced bool
+		expected    []int
+	}{
+		{"differenced spike and shift",
+			[]float64{3, 0, 0, 8, -8, 0, 4, 0, 0, 0}, true, []int{6}},
+		{"differenced uneven undo is a shift",
+			[]float64{0, 0, 0, 8, -2, 0, 0, 0, 0, 0}, true, []int{3, 4}},
+		{"spike and sustained run",
+			[]float64{0, 0, 0, 8, 0, 0, 4, 4, 4, 4}, false, []int{6}},
+		{"run changing direction",
+			[]float64{0, 0, 0, 8, -8, 0, 4, 4, 4, 0}, false, []int{6}},
+	}
+	for _, test := range tests {
+		shifts := findLevelShifts(series, test.s, test.differenced, 3)
+		var observed []int
+		for _, shift := range shifts {
+			observed = append(observed, shift.Index)
+		}
+		if !reflect.DeepEqual(observed, test.expected) {
+			t.Errorf("Failed '%v'. Expected shifts at %v but got %v",
+				test.description, test.expected, observed)
+		}
+	}
+}
+
+func TestLevelShifts(t *testing.T) {
+	series := seasonalSeries(84)
+	series[20] += 50
+	for i := 40; i < len(series); i++ {
+		series[i] += 30
+	}
+	anoms := FindAnomalies(series, AutoDiff(false), ForceDiff(true),
+		LevelShifts(true))
+	found := false
+	for _, shift := range anoms.LevelShifts {
+		if shift.Index == 20 || shift.Index == 21 {
+			t.Errorf("Expected the spike at 20 not to be a level shift")
+		}
+		if shift.Index == 40 {
+			found = true
+			if math.Abs(shift.Before-10) > 1 || math.Abs(shift.After-40) > 1 {
+				t.Errorf("Expected the level to shift from about 10 to 40, but got "+
+					"%v to %v", shift.Before, shift.After)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected a level shift at 40, but got %v", anoms.LevelShifts)
+	}
+}
@@ -0,0 +1,45 @@
+package rpca
+
+import (
+	"github.com/gonum/matrix/mat64"
+	"math"
+	"testing"
+)
+
+func TestHuber(t *testing.T) {
+	tests := []struct{ x, delta, expected float64 }{
+		{0.5, 1, 0.125},
+		{-0.5, 1, 0.125},
+		{3, 1, 2.5},
+		{-3, 1, 2.5},
+		{3, math.Inf(1), 4.5},
+	}
+	for _, test := range tests {
+		if observed := huber(test.x, test.delta); observed != test.expected {
+			t.Errorf("Failed Huber loss of %v with delta %v. Expected %v but got %v",
+				test.x, test.delta, test.expected, observed)
+		}
+	}
+}
+
+func TestHuberNoiseModel(t *testing.T) {
+	test := rpcaTestCases[2]
+	flagged := func(loss Loss) int {
+		conf := *test.options
+		conf.noise = loss
+		decomposed := computeRPCA(mat64.DenseCopyOf(test.timeSeries), &conf)
+		n := 0
+		for _, v := range decomposed.unfold(decomposed.S) {
+			if v != 0 {
+				n++
+			}
+		}
+		return n
+	}
+	gaussian, robust := flagged(Gaussian()), flagged(Huber(1))
+	if robust >= gaussian {
+		t.Errorf("Failed '%v'. Expected Huber noise to flag fewer than the %v "+
+			"points flagged with Gaussian noise, but it flagged %v",
+			test.description, gaussian, robust)
+	}
+}
@@ -0,0 +1,145 @@
+package rpca
+
+import (
+	"log/slog"
+	"math"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// The non-convex solvers stop once the squared size of the noise component
+// changes by less than this fraction of the squared size of the data.
+const nonconvexTolerance = 1e-8
+
+// solveGoDec runs GoDec (Zhou and Tao, "GoDec: Randomized Low-rank & Sparse
+// Matrix Decomposition in Noisy Case"), alternating between the best rank-k
+// approximation of M - S and keeping the largest entries of M - L as S.
+func solveGoDec(mat rPCAable, conf *rpcaConfig, logger *slog.Logger) solution {
+	rows, cols := mat.Dims()
+	if conf.rank < 1 {
+		panic("Rank less than one")
+	}
+	budget := int(conf.sparsity * float64(rows*cols))
+	previous := math.Pow(mat64.Norm(mat, 2), 2)
+	total := nonconvexTolerance * previous
+	difference := 2 * total
+
+	if logger != nil {
+		logger.Debug("rpca: initial state",
+			"rows", rows,
+			"cols", cols,
+			"rank", conf.rank,
+			"budget", budget,
+			"matrix", mat64.Formatted(mat))
+	}
+
+	residual := mat64.NewDense(rows, cols, nil)
+	s := mat64.NewDense(rows, cols, nil)
+	var l, e *mat64.Dense
+	iter := 0
+
+	for difference > total && iter < MAX_ITERS {
+		residual.Sub(mat, s)
+		var values []float64
+		l, values = projectRank(residual, conf.rank)
+		s = mat64.NewDense(rows, cols, nil)
+		s.Sub(mat, l)
+		keepLargest(s, budget)
+		eComp := computeE(mat, l, s, math.Inf(1), nil)
+		e = eComp.matrix
+
+		difference = math.Abs(previous - eComp.norm)
+		previous = eComp.norm
+		conf.report(nonconvexStats(iter, conf.rank, values, s, eComp.norm,
+			difference), logger)
+		iter++
+	}
+	return solution{l, s, e, iter < MAX_ITERS, iter}
+}
+
+// solveAltProj runs AltProj (Netrapalli et al., "Non-convex Robust PCA"). In
+// stage k it alternates between the best rank-k approximation of M - S and
+// hard thresholding M - L, with a threshold that starts between the k-th and
+// (k+1)-th singular values and decays towards the latter.
+func solveAltProj(mat rPCAable, conf *rpcaConfig, logger *slog.Logger) solution {
+	rows, cols := mat.Dims()
+	if conf.rank < 1 {
+		panic("Rank less than one")
+	}
+	beta := conf.sPenalty / 1.4
+	budget := int(conf.sparsity * float64(rows*cols))
+	_, values := projectRank(mat, 0)
+	s := hardThreshold(mat, beta*values[0])
+	keepLargest(s, budget)
+
+	if logger != nil {
+		logger.Debug("rpca: initial state",
+			"rows", rows,
+			"cols", cols,
+			"rank", conf.rank,
+			"budget", budget,
+			"beta", beta,
+			"matrix", mat64.Formatted(mat))
+	}
+
+	residual := mat64.NewDense(rows, cols, nil)
+	var l, e *mat64.Dense
+	iter := 0
+
+	for stage := 1; stage <= conf.rank && iter < MAX_ITERS; stage++ {
+		previous := math.Pow(mat64.Norm(mat, 2), 2)
+		total := nonconvexTolerance * previous
+		difference := 2 * total
+		for t := 0; difference > total && iter < MAX_ITERS; t++ {
+			residual.Sub(mat, s)
+			l, values = projectRank(residual, stage)
+			threshold := beta * (singularValue(values, stage) +
+				math.Pow(0.5, float64(t))*singularValue(values, stage-1))
+			residual.Sub(mat, l)
+			s = hardThreshold(residual, threshold)
+			keepLargest(s, budget)
+			eComp := computeE(mat, l, s, math.Inf(1), nil)
+			e = eComp.matrix
+
+			difference = math.Abs(previous - eComp.norm)
+			previous = eComp.norm
+			stats := nonconvexStats(iter, stage, values, s, eComp.norm, difference)
+			stats.SPenalty = threshold
+			conf.report(stats, logger)
+			iter++
+		}
+	}
+	return solution{l, s, e, iter < MAX_ITERS, iter}
+}
+
+// singularValue returns the i-th (zero-based) singular value, or zero if the
+// matrix has fewer singular values.
+func singularValue(values []float64, i int) float64 {
+	if i < len(values) {
+		return values[i]
+	}
+	return 0
+}
+
+// nonconvexStats describes an iteration of the non-convex solvers, whose
+// objective is the squared size of the noise component alone.
+func nonconvexStats(iter, rank int, values []float64, s *mat64.Dense,
+	eNorm, difference float64) IterationStats {
+	kept := values[:int(math.Min(float64(rank), float64(len(values))))]
+	rank = 0
+	for _, v := range kept {
+		if v != 0 {
+			rank++
+		}
+	}
+	return IterationStats{
+		Iteration:  iter,
+		LNorm:      sum(kept),
+		SNorm:      l1Norm(s),
+		ENorm:      eNorm,
+		Objective:  eNorm,
+		Difference: difference,
+		Rank:       rank,
+		Sparsity:   sparsity(s),
+	}
+}
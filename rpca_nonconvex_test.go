@@ -0,0 +1,38 @@
+package rpca
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNonconvexSolvers(t *testing.T) {
+	series := lowRankSeries(20)
+	for i := range series {
+		series[i] += 0.01 * math.Cos(float64(i*i))
+	}
+	series[30] += 20
+	series[101] -= 15
+	tests := []struct {
+		description string
+		options     []func(*rpcaConfig) error
+	}{
+		{"GoDec", []func(*rpcaConfig) error{
+			Solver(GoDec), Sparsity(2.0 / 140), Scale(false)}},
+		{"AltProj", []func(*rpcaConfig) error{Solver(AltProj), Scale(false)}},
+		{"AltProj rank 2", []func(*rpcaConfig) error{Solver(AltProj), Rank(2)}},
+	}
+	for _, test := range tests {
+		options := append(test.options, AutoDiff(false))
+		anoms := FindAnomalies(series, options...)
+		for i, p := range anoms.Positions {
+			if p != (i == 30 || i == 101) {
+				t.Errorf("Failed '%v' on point %v. Expected anomalous to be %v, "+
+					"but got %v (%v)", test.description, i, !p, p, anoms.Values[i])
+			}
+		}
+		if math.Abs(anoms.Values[30]-20) > 0.5 || math.Abs(anoms.Values[101]+15) > 0.5 {
+			t.Errorf("Failed '%v' on anomaly sizes. Expected 20 and -15, but got "+
+				"%v and %v", test.description, anoms.Values[30], anoms.Values[101])
+		}
+	}
+}
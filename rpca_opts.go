@@ -1,20 +1,55 @@
 package rpca
 
+import (
+	"errors"
+	"log/slog"
+	"time"
+)
+
 type rpcaConfig struct {
-	frequency int
-	autodiff  bool
-	forcediff bool
-	scale     bool
-	lPenalty  float64
-	sPenalty  float64
-	verbose   bool
+	frequency  int
+	autodiff   bool
+	forcediff  bool
+	scale      bool
+	lPenalty   float64
+	sPenalty   float64
+	verbose    bool
+	pValues    bool
+	bootstrap  int
+	seed       int64
+	confidence float64
+	keepNoise  bool
+
+	onIteration func(IterationStats)
+	logger      *slog.Logger
+	diagnostics bool
+	solver      Algorithm
+	rank        int
+	sparsity    float64
+
+	outlierPursuit bool
+	levelShifts    bool
+	noise          Loss
+	weights        []float64
+	timestamps     []time.Time
+	exclusions     []exclusion
+	calendar       *Calendar
+	holidayMode    HolidayMode
+	location       *time.Location
+	monthly        bool
+	phaseOffset    int
+	seasonStart    time.Time
+	counts         bool
+	state          *State
+	stateShift     int
 }
 
 // Frequency informs the algorithm of the major frequency of the time series to
 // use for analysis. For example, if you have 56 points of daily measurements,
 // the major frequency is likely 7, which would capture the weekly trend. Note
 // that due to the nature of the algorithm, the length of the provided time
-// series must be divisible by the frequency.
+// series must be divisible by the frequency, unless it is folded with an
+// option such as PhaseOffset or Location.
 func Frequency(freq int) func(*rpcaConfig) error {
 	return func(conf *rpcaConfig) error {
 		conf.frequency = freq
@@ -78,10 +113,376 @@ func SPenalty(penalty float64) func(*rpcaConfig) error {
 	}
 }
 
-// If true, print lots of information about each iteration of the algorithm.
+// If true, log lots of information about each iteration of the algorithm to
+// standard error. Prefer Logger or OnIteration to route this information
+// elsewhere.
 func Verbose(active bool) func(*rpcaConfig) error {
 	return func(conf *rpcaConfig) error {
 		conf.verbose = active
 		return nil
 	}
 }
+
+// OnIteration registers a function that is called with the state of the
+// solver after every iteration of the algorithm. This is useful for inspecting
+// convergence in tests or exporting it as metrics.
+func OnIteration(fn func(IterationStats)) func(*rpcaConfig) error {
+	return func(conf *rpcaConfig) error {
+		conf.onIteration = fn
+		return nil
+	}
+}
+
+// Logger sets where the algorithm logs its progress. The initial state and the
+// IterationStats of every iteration are logged at debug level, so the logger's
+// handler must be enabled for slog.LevelDebug for anything to show up.
+func Logger(logger *slog.Logger) func(*rpcaConfig) error {
+	return func(conf *rpcaConfig) error {
+		conf.logger = logger
+		return nil
+	}
+}
+
+// If true, compute a Benjamini-Hochberg adjusted p-value for every point in
+// the time series. Each point's deviation from the low-rank fit is compared
+// against a normal distribution with the spread of the noise component (E),
+// estimated from its median absolute deviation, so a small p-value means the
+// deviation is unlikely to be ordinary noise.
+func PValues(active bool) func(*rpcaConfig) error {
+	return func(conf *rpcaConfig) error {
+		conf.pValues = active
+		return nil
+	}
+}
+
+// The number of block bootstrap resamples to use when computing p-values. Each
+// resample draws whole seasonal periods of the noise component with
+// replacement, which preserves the within-period correlation of the noise, and
+// estimates the spread of the noise from them. P-values are averaged over the
+// resamples, so they account for how uncertain the spread is. A value of zero,
+// the default, estimates the spread once from the noise component as is. Only
+// used when PValues is set.
+func Bootstrap(resamples int) func(*rpcaConfig) error {
+	return func(conf *rpcaConfig) error {
+		conf.bootstrap = resamples
+		return nil
+	}
+}
+
+// The seed for any randomized part of the analysis, such as bootstrapping.
+// Runs with the same seed and options produce identical results.
+func Seed(seed int64) func(*rpcaConfig) error {
+	return func(conf *rpcaConfig) error {
+		conf.seed = seed
+		return nil
+	}
+}
+
+// The coverage of the prediction intervals returned by Forecast, between 0 and
+// 1. The default of 0.95 gives intervals that should contain 95% of future
+// points when the noise is roughly normal. SeasonalESD tests at a significance
+// level of one minus the confidence.
+func Confidence(level float64) func(*rpcaConfig) error {
+	return func(conf *rpcaConfig) error {
+		conf.confidence = level
+		return nil
+	}
+}
+
+// If true, Clean keeps the noise component (E) when replacing anomalous points,
+// so cleaned stretches have the same texture as the rest of the series instead
+// of following the smooth low-rank fit exactly.
+func KeepNoise(active bool) func(*rpcaConfig) error {
+	return func(conf *rpcaConfig) error {
+		conf.keepNoise = active
+		return nil
+	}
+}
+
+// If true, record the IterationStats of every iteration of the algorithm and
+// attach them to the result as Diagnostics.
+func CollectDiagnostics(active bool) func(*rpcaConfig) error {
+	return func(conf *rpcaConfig) error {
+		conf.diagnostics = active
+		return nil
+	}
+}
+
+// Algorithm is a method of decomposing the folded time series into low-rank,
+// sparse and noise components.
+type Algorithm int
+
+const (
+	// Surus is the alternating soft-thresholding algorithm used by Netflix's
+	// Surus project, with a convergence rate (mu) that adapts to the noise. It
+	// is the default.
+	Surus Algorithm = iota
+
+	// InexactALM is Principal Component Pursuit solved with inexact augmented
+	// Lagrange multipliers (Lin, Chen and Ma), as analyzed by Candes et al. It
+	// has well understood convergence guarantees but models no noise: the
+	// series is split exactly into low-rank and sparse components.
+	InexactALM
+
+	// AltProj alternates between projecting onto matrices of a given Rank and
+	// hard thresholding the sparse component, raising the rank one stage at a
+	// time (Netrapalli et al.). The thresholds are set relative to the
+	// singular values of the data and scaled by the S penalty.
+	AltProj
+
+	// GoDec alternates between projecting onto matrices of a given Rank and
+	// keeping only the largest entries of the sparse component, as allowed by
+	// the Sparsity budget (Zhou and Tao). It is typically the fastest solver
+	// on long series.
+	GoDec
+)
+
+// Solver chooses the algorithm used to decompose the time series. The same
+// folding, differencing, scaling and output apply to every algorithm.
+func Solver(algorithm Algorithm) func(*rpcaConfig) error {
+	return func(conf *rpcaConfig) error {
+		conf.solver = algorithm
+		return nil
+	}
+}
+
+// The rank of the low-rank component for the AltProj and GoDec solvers. For
+// example, a rank of 2 lets the seasonal profile have two independent shapes,
+// such as a weekday and a weekend pattern. Defaults to 1. Note that scaling
+// centers the series, so a profile that grows with the level of the series
+// needs one more rank unless Scale is off.
+func Rank(k int) func(*rpcaConfig) error {
+	return func(conf *rpcaConfig) error {
+		conf.rank = k
+		return nil
+	}
+}
+
+// The largest fraction of points that the AltProj and GoDec solvers may assign
+// to the sparse component. GoDec always fills the budget, while AltProj only
+// uses it as a cap on what its thresholds let through. Defaults to 0.05.
+func Sparsity(fraction float64) func(*rpcaConfig) error {
+	return func(conf *rpcaConfig) error {
+		conf.sparsity = fraction
+		return nil
+	}
+}
+
+// If true, detect whole anomalous periods instead of individual points
+// (Outlier Pursuit, Xu et al.). The sparse component is shrunk one period at a
+// time, so a period is either flagged as a whole or not at all, and the
+// flagged periods are reported in Anomalies.Periods. This suits incidents that
+// corrupt an entire week rather than a few isolated points. Only the Surus and
+// InexactALM solvers support this.
+func OutlierPursuit(active bool) func(*rpcaConfig) error {
+	return func(conf *rpcaConfig) error {
+		conf.outlierPursuit = active
+		return nil
+	}
+}
+
+// If true, tell lasting level shifts apart from transient spikes and report
+// them in Anomalies.LevelShifts. Whether a shift is recognized by a single
+// anomalous change or a sustained run of anomalous points depends on whether
+// the series was differenced (see AutoDiff).
+func LevelShifts(active bool) func(*rpcaConfig) error {
+	return func(conf *rpcaConfig) error {
+		conf.levelShifts = active
+		return nil
+	}
+}
+
+// NoiseModel chooses the loss used for the noise component (E). The default is
+// Gaussian; see Huber for bursty metrics. Only the Surus solver supports Huber
+// noise; combining it with another solver panics.
+func NoiseModel(loss Loss) func(*rpcaConfig) error {
+	return func(conf *rpcaConfig) error {
+		conf.noise = loss
+		return nil
+	}
+}
+
+// Weights sets how much every point of the time series can be trusted, for
+// example to discount partial buckets or backfilled data. Weights are relative
+// and scaled so that the largest is one. A point's weight scales how much it
+// counts towards the low-rank fit and divides the threshold it must exceed to
+// be flagged, so a point with a weight of zero is ignored by the fit and never
+// flagged. There must be one weight per point. Only the Surus solver supports
+// this; combining it with another solver panics.
+func Weights(weights []float64) func(*rpcaConfig) error {
+	return func(conf *rpcaConfig) error {
+		conf.weights = weights
+		return nil
+	}
+}
+
+// Timestamps sets the time of every point in the time series, so that options
+// such as ExcludeTimes can refer to points by time. There must be one
+// timestamp per point, in increasing order.
+func Timestamps(timestamps []time.Time) func(*rpcaConfig) error {
+	return func(conf *rpcaConfig) error {
+		conf.timestamps = timestamps
+		return nil
+	}
+}
+
+// Exclude masks the points from index start up to but not including index end
+// from detection, for example to cover planned maintenance or a launch. The
+// points neither count towards the low-rank fit nor get flagged. Instead, their
+// deviation from the fit is reported in Anomalies.ExpectedDeviations. The fit
+// for excluded points comes from the rest of their period, so a range covering
+// a whole period is fit towards the mean of the series instead. Pass the option
+// several times to exclude several ranges. Only the Surus solver supports
+// this; combining it with another solver panics.
+func Exclude(start, end int) func(*rpcaConfig) error {
+	return func(conf *rpcaConfig) error {
+		conf.exclusions = append(conf.exclusions, exclusion{start: start, end: end})
+		return nil
+	}
+}
+
+// ExcludeTimes is like Exclude, but masks the points whose timestamp is at or
+// after start and before end. It requires the Timestamps option.
+func ExcludeTimes(start, end time.Time) func(*rpcaConfig) error {
+	return func(conf *rpcaConfig) error {
+		conf.exclusions = append(conf.exclusions,
+			exclusion{from: start, to: end, byTime: true})
+		return nil
+	}
+}
+
+// HolidayMode is how the Holidays option treats holidays.
+type HolidayMode int
+
+const (
+	// MaskHolidays excludes holidays from detection, as the Exclude option
+	// does: they neither count towards the low-rank fit nor get flagged, and
+	// their deviation from the fit is reported in
+	// Anomalies.ExpectedDeviations.
+	MaskHolidays HolidayMode = iota
+
+	// HolidayPattern moves holidays out of their usual slot in the period and
+	// into a row of their own, so the low-rank component learns a holiday
+	// pattern, such as lower traffic than a normal weekday, that scales with
+	// the rest of the period. Holidays are still flagged when they depart from
+	// that pattern. A period with several holidays gets one row per holiday.
+	HolidayPattern
+)
+
+// Holidays makes detection aware of the holidays in a calendar, such as one
+// from RegionCalendar or LoadCalendar, so that they are not flagged every year
+// for departing from the usual weekly pattern. A point is a holiday if its day,
+// in the location of its timestamp, is in the calendar. It requires the
+// Timestamps option. Only the Surus solver supports this; combining it with
+// another solver panics.
+func Holidays(calendar *Calendar, mode HolidayMode) func(*rpcaConfig) error {
+	return func(conf *rpcaConfig) error {
+		conf.calendar = calendar
+		conf.holidayMode = mode
+		return nil
+	}
+}
+
+// Location folds the time series by local wall-clock time in loc rather than
+// by counting points, so that, for example, hourly traffic that follows local
+// business hours lines up across daylight saving time transitions. Every
+// period is frequency sampling intervals of wall-clock time, and the sampling
+// interval is the most common spacing of the timestamps. The slot for the hour
+// skipped in spring is filled in with the median of its row, and the two
+// points of the hour repeated in autumn share a slot and are fit by their mean.
+// Gaps in the series are filled in too, so the length of the series need not
+// be divisible by the frequency. It requires the Timestamps option.
+func Location(loc *time.Location) func(*rpcaConfig) error {
+	return func(conf *rpcaConfig) error {
+		conf.location = loc
+		return nil
+	}
+}
+
+// Monthly folds a daily time series by day of the month, with one period per
+// calendar month, so that monthly cycles such as billing runs can be learned
+// even though months differ in length. Days past the end of shorter months are
+// masked. The frequency is not used to fold, and the series need not cover
+// whole months, but it should span a couple of years for the monthly pattern
+// to be learned rather than flagged. Dates are taken in the Location, if set.
+// It requires the Timestamps option. Only the Surus solver supports this;
+// combining it with another solver panics.
+func Monthly(active bool) func(*rpcaConfig) error {
+	return func(conf *rpcaConfig) error {
+		conf.monthly = active
+		return nil
+	}
+}
+
+// PhaseOffset sets the slot of the period that the first point of the time
+// series falls in, so that periods line up with a canonical start rather than
+// with the start of the series. For example, with a frequency of 7 and periods
+// starting on Monday, a daily series starting on a Wednesday has an offset of
+// 2. The slots before the first point and after the last are filled in with
+// the typical value of their slot, so the length of the series need not be
+// divisible by the frequency. Results are still reported by the index of each
+// point in the series.
+func PhaseOffset(offset int) func(*rpcaConfig) error {
+	return func(conf *rpcaConfig) error {
+		conf.phaseOffset = offset
+		return nil
+	}
+}
+
+// SeasonStart is like PhaseOffset, but derives the offset from the timestamps:
+// start is any time at which a period starts, such as midnight on some Monday,
+// and the offset is the number of sampling intervals from it to the first
+// timestamp. It requires the Timestamps option, and counts wall-clock time
+// with the Location option.
+func SeasonStart(start time.Time) func(*rpcaConfig) error {
+	return func(conf *rpcaConfig) error {
+		conf.seasonStart = start
+		return nil
+	}
+}
+
+// Counts treats the time series as event counts, such as errors per minute,
+// whose noise grows with their rate. The counts are Anscombe transformed
+// before scaling, which brings the variance of Poisson noise close to one
+// whatever the rate, so a jump in a quiet stretch is weighed against the
+// smaller noise there rather than against that of the busy stretches. The
+// transform only approximately stabilizes the variance, and does so poorly
+// for rates below about 4, where the noise ends up smaller than elsewhere and
+// small jumps stand out more than they should. Values, forecasts and cleaned
+// series are mapped back to counts. Counts must not be negative.
+func Counts(active bool) func(*rpcaConfig) error {
+	return func(conf *rpcaConfig) error {
+		conf.counts = active
+		return nil
+	}
+}
+
+/*
+WarmStart seeds the solver with the low-rank component and convergence rate of
+the previous run, saved in state, and saves this run's there for the next. It
+is meant for detection that re-runs every few minutes on a sliding window:
+shift is the number of points the window has moved since the previous run,
+whose low-rank component is shifted to match, with new points at the end
+repeating the value of a period before. Since consecutive windows mostly
+overlap, the solver starts close to where it will end up and needs far fewer
+iterations. The first run with a new State starts cold, as does a run that
+differences the series when the previous one did not, or vice versa.
+
+Only the Surus solver supports this; combining it with another solver panics,
+as do a nil state and a negative shift. The state must not be shared between
+series, or between runs that may happen at the same time, such as those of
+Cube.FindAnomalies or GridSearch.
+*/
+func WarmStart(state *State, shift int) func(*rpcaConfig) error {
+	return func(conf *rpcaConfig) error {
+		if state == nil {
+			return errors.New("warm start state is nil")
+		}
+		if shift < 0 {
+			return errors.New("warm start shift is negative")
+		}
+		conf.state = state
+		conf.stateShift = shift
+		return nil
+	}
+}
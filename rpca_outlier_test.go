@@ -0,0 +1,60 @@
+package rpca
+
+import "testing"
+
+func TestOutlierPursuit(t *testing.T) {
+	tests := []struct {
+		solver Algorithm
+		series []float64
+	}{
+		{Surus, seasonalSeries(140)},
+		// Inexact ALM models no noise, so it needs a noiseless series.
+		{InexactALM, lowRankSeries(20)},
+	}
+	for _, test := range tests {
+		for i := 28; i < 35; i++ {
+			test.series[i] += 3 * float64(i%3)
+		}
+		anoms := FindAnomalies(test.series, AutoDiff(false), OutlierPursuit(true),
+			Solver(test.solver))
+		if len(anoms.Periods) != 20 {
+			t.Fatalf("Expected 20 periods but got %v", len(anoms.Periods))
+		}
+		for j, p := range anoms.Periods {
+			if p != (j == 4) {
+				t.Errorf("Failed solver %v on period %v. Expected anomalous to be %v "+
+					"but got %v", test.solver, j, !p, p)
+			}
+		}
+		for i, p := range anoms.Positions {
+			if p != anoms.Periods[i/7] {
+				t.Errorf("Failed solver %v on point %v. Expected it to match its "+
+					"period", test.solver, i)
+			}
+		}
+	}
+}
+
+func TestOutlierPursuitWithExclude(t *testing.T) {
+	series := seasonalSeries(140)
+	for i := 28; i < 35; i++ {
+		series[i] += 3 * float64(i%3)
+	}
+	anoms := FindAnomalies(series, AutoDiff(false), OutlierPursuit(true),
+		Exclude(29, 32))
+	if !anoms.Periods[4] {
+		t.Errorf("Expected period 4 to be anomalous")
+	}
+	for i, p := range anoms.Positions {
+		expected := i >= 28 && i < 35 && !anoms.Excluded[i]
+		if p != expected {
+			t.Errorf("Failed point %v. Expected anomalous to be %v but got %v",
+				i, expected, p)
+		}
+	}
+	for i := 29; i < 32; i++ {
+		if !anoms.Excluded[i] {
+			t.Errorf("Expected point %v to be excluded", i)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package rpca
+
+import "math"
+
+// Driver is which series of a ratio drove an anomaly in it.
+type Driver int
+
+const (
+	// NoDriver is reported for points that were not anomalous.
+	NoDriver Driver = iota
+
+	// NumeratorDriver means the numerator departed further from what was
+	// expected of it than the denominator did, such as a burst of errors.
+	NumeratorDriver
+
+	// DenominatorDriver means the denominator departed further from what was
+	// expected of it than the numerator did, such as a drop in traffic with
+	// the usual number of errors.
+	DenominatorDriver
+)
+
+func (d Driver) String() string {
+	switch d {
+	case NumeratorDriver:
+		return "numerator"
+	case DenominatorDriver:
+		return "denominator"
+	default:
+		return "none"
+	}
+}
+
+type RatioAnomalies struct {
+	// Anomalies in the ratio of the numerator to the denominator, in the
+	// domain of the ratio.
+	Anomalies
+
+	// Ratios holds the ratio at every point, or zero where the denominator is
+	// zero.
+	Ratios []float64
+
+	// Drivers holds, for every anomalous point, which series drove the anomaly.
+	Drivers []Driver
+
+	// NumeratorDiagnostics and DenominatorDiagnostics record how decomposing
+	// the numerator and the denominator on their own converged, next to the
+	// Diagnostics of the ratio. Only populated when the CollectDiagnostics
+	// option is set.
+	NumeratorDiagnostics, DenominatorDiagnostics *Diagnostics
+}
+
+/*
+FindRatioAnomalies finds anomalies in a ratio metric, such as an error rate or
+a conversion rate, from its numerator and denominator. Running FindAnomalies on
+the ratio alone treats every point alike, but a ratio over a small denominator
+is far noisier than one over a large denominator. Here every point is weighted
+by its denominator, as with the Weights option, so a quiet bucket with one
+error in ten requests counts for little and must depart much further to be
+flagged. Points with a denominator of zero are ignored.
+
+Every anomaly is attributed to the numerator or the denominator, whichever
+departed further, relative to its size, from what decomposing it on its own
+expects. It takes the same options as FindAnomalies; Weights are multiplied by
+the denominators, and Counts applies only to the numerator and denominator.
+Only the Surus solver supports the weighting, so combining this with another
+solver panics.
+
+The ratio, the numerator and the denominator are decomposed in that order, so
+OnIteration is called for each of them in turn. Everything logged is tagged
+with a "series" attribute naming which one it belongs to.
+*/
+func FindRatioAnomalies(numerator, denominator []float64,
+	options ...func(*rpcaConfig) error) RatioAnomalies {
+	if len(numerator) != len(denominator) {
+		panic("Numerator and denominator of different lengths")
+	}
+	ratios := make([]float64, len(numerator))
+	total, volume := 0.0, 0.0
+	for i, d := range denominator {
+		if d < 0 {
+			panic("Negative denominator")
+		}
+		if d > 0 {
+			ratios[i] = numerator[i] / d
+			total += numerator[i]
+			volume += d
+		}
+	}
+
+	conf := newConfig(ratios, options)
+	if conf.solver != Surus {
+		panic("Ratio anomalies require the Surus solver")
+	}
+	weights := normalizeWeights(denominator, len(denominator))
+	if conf.weights != nil {
+		for i, w := range normalizeWeights(conf.weights, len(weights)) {
+			weights[i] *= w
+		}
+	}
+	series := append([]float64(nil), ratios...)
+	for i, d := range denominator {
+		if d == 0 && volume > 0 {
+			// Masked by its zero weight, but kept from skewing the test for
+			// stationarity.
+			series[i] = total / volume
+		}
+	}
+	ratioConf := conf
+	ratioConf.weights = weights
+	ratioConf.counts = false
+	ratioConf.tag("ratio")
+	anomalies := RatioAnomalies{
+		Anomalies: findAnomalies(series, &ratioConf),
+		Ratios:    ratios,
+		Drivers:   make([]Driver, len(ratios)),
+	}
+
+	// The warm start, if any, follows the ratio.
+	conf.state = nil
+	expectedNumerator, numeratorDiagnostics := expected(numerator, conf,
+		"numerator")
+	expectedDenominator, denominatorDiagnostics := expected(denominator, conf,
+		"denominator")
+	anomalies.NumeratorDiagnostics = numeratorDiagnostics
+	anomalies.DenominatorDiagnostics = denominatorDiagnostics
+	for i, p := range anomalies.Positions {
+		if !p {
+			continue
+		}
+		anomalies.Drivers[i] = DenominatorDriver
+		if relativeDeviation(numerator[i], expectedNumerator[i]) >
+			relativeDeviation(denominator[i], expectedDenominator[i]) {
+			anomalies.Drivers[i] = NumeratorDriver
+		}
+	}
+	return anomalies
+}
+
+// expected returns what every point of the series was expected to be: the
+// point less its sparse and noise components, and the Diagnostics of its
+// decomposition if they are collected. What is logged is tagged with the name
+// of the series.
+func expected(series []float64, conf rpcaConfig, name string) ([]float64,
+	*Diagnostics) {
+	conf.tag(name)
+	var diagnostics *Diagnostics
+	if conf.diagnostics {
+		diagnostics = conf.record()
+	}
+	transformed := conf.transform(series)
+	decomposed := decompose(transformed, &conf)
+	s := decomposed.unfold(decomposed.S)
+	e := decomposed.unfold(decomposed.E)
+	if decomposed.differenced {
+		// The first value only pads the differenced series.
+		s[0], e[0] = 0, 0
+	}
+	fitted := make([]float64, len(series))
+	for i, v := range transformed {
+		fitted[i] = conf.untransform(v - s[i] - e[i])
+	}
+	if diagnostics != nil {
+		diagnostics.Converged = decomposed.converged
+	}
+	return fitted, diagnostics
+}
+
+// relativeDeviation returns how far a value is from what was expected, as a
+// fraction of what was expected.
+func relativeDeviation(value, expected float64) float64 {
+	if expected == 0 {
+		return math.Abs(value)
+	}
+	return math.Abs(value-expected) / math.Abs(expected)
+}
@@ -0,0 +1,88 @@
+package rpca
+
+import (
+	"bytes"
+	"log/slog"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestFindRatioAnomalies(t *testing.T) {
+	requests := make([]float64, 84)
+	errors := make([]float64, len(requests))
+	for i := range requests {
+		requests[i] = 1000 + 400*math.Sin(2*math.Pi*float64(i)/7)
+		errors[i] = math.Round(0.02*requests[i] + 2*math.Cos(float64(i*i)))
+	}
+	// A burst of errors, a drop in traffic with the usual number of errors,
+	// and a quiet bucket with one error in ten requests.
+	burst, drop, quiet := 30, 45, 60
+	errors[burst] *= 3
+	requests[drop] /= 3
+	requests[quiet], errors[quiet] = 10, 1
+
+	ratios := make([]float64, len(requests))
+	for i := range ratios {
+		ratios[i] = errors[i] / requests[i]
+	}
+	if unweighted := FindAnomalies(ratios, AutoDiff(false)); !unweighted.Positions[quiet] {
+		t.Errorf("Expected the quiet bucket to be flagged when ignoring volume")
+	}
+
+	anoms := FindRatioAnomalies(errors, requests, AutoDiff(false))
+	if anoms.Positions[quiet] {
+		t.Errorf("Expected the quiet bucket not to be flagged")
+	}
+	tests := []struct {
+		index  int
+		driver Driver
+	}{{burst, NumeratorDriver}, {drop, DenominatorDriver}}
+	for _, test := range tests {
+		if !anoms.Positions[test.index] {
+			t.Errorf("Expected %v to be flagged", test.index)
+		}
+		if anoms.Drivers[test.index] != test.driver {
+			t.Errorf("Expected %v to be driven by the %v, but got %v", test.index,
+				test.driver, anoms.Drivers[test.index])
+		}
+	}
+	if anoms.Ratios[burst] != ratios[burst] {
+		t.Errorf("Expected ratio %v but got %v", ratios[burst], anoms.Ratios[burst])
+	}
+}
+
+func TestRatioDiagnostics(t *testing.T) {
+	requests := make([]float64, 56)
+	errors := make([]float64, len(requests))
+	for i := range requests {
+		requests[i] = 1000 + 400*math.Sin(2*math.Pi*float64(i)/7)
+		errors[i] = math.Round(0.02 * requests[i])
+	}
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf,
+		&slog.HandlerOptions{Level: slog.LevelDebug}))
+	iterations := 0
+	anoms := FindRatioAnomalies(errors, requests, AutoDiff(false),
+		CollectDiagnostics(true), Logger(logger),
+		OnIteration(func(IterationStats) { iterations++ }))
+
+	all := []*Diagnostics{anoms.Diagnostics, anoms.NumeratorDiagnostics,
+		anoms.DenominatorDiagnostics}
+	recorded := 0
+	for i, diagnostics := range all {
+		if diagnostics == nil || len(diagnostics.Iterations) == 0 {
+			t.Fatalf("Expected diagnostics %v to record iterations", i)
+		}
+		recorded += len(diagnostics.Iterations)
+	}
+	if recorded != iterations {
+		t.Errorf("Expected the diagnostics to record all %v iterations but got %v",
+			iterations, recorded)
+	}
+	for _, series := range []string{"ratio", "numerator", "denominator"} {
+		if !strings.Contains(buf.String(), "series="+series) {
+			t.Errorf("Expected the %v to be tagged in the log", series)
+		}
+	}
+}
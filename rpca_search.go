@@ -0,0 +1,262 @@
+package rpca
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Params is one combination of the options tuned by GridSearch and
+// RandomSearch.
+type Params struct {
+	Frequency int
+	LPenalty  float64
+
+	// SPenalty is zero for the default penalty, which depends on the
+	// frequency and the length of the series.
+	SPenalty float64
+
+	AutoDiff bool
+	Scale    bool
+}
+
+// Options returns the options that select the parameters, to be passed to
+// FindAnomalies or any other function that takes its options.
+func (p Params) Options() []func(*rpcaConfig) error {
+	options := []func(*rpcaConfig) error{
+		Frequency(p.Frequency),
+		LPenalty(p.LPenalty),
+		AutoDiff(p.AutoDiff),
+		Scale(p.Scale),
+	}
+	if p.SPenalty != 0 {
+		options = append(options, SPenalty(p.SPenalty))
+	}
+	return options
+}
+
+// SearchSpace holds the values to try for every tuned option. An empty slice
+// leaves the option as the search's other options set it, or at its default.
+type SearchSpace struct {
+	Frequencies []int
+	LPenalties  []float64
+	SPenalties  []float64
+	AutoDiff    []bool
+	Scale       []bool
+}
+
+// Objective scores the anomalies found in every series by one combination of
+// options, in the order the series were given. Higher is better.
+type Objective func(found []Anomalies) float64
+
+// Trial is a combination of options that was tried and its score.
+type Trial struct {
+	Params Params
+	Score  float64
+
+	// Err is why the trial failed, such as a frequency that does not divide
+	// the length of a series, or nil if it did not. Failed trials score NaN.
+	Err error
+}
+
+// LabelObjective scores anomalies by their mean F1 against labels that mark
+// the anomalous points of every series. Series without anomalous points or
+// detections score 1.
+func LabelObjective(labels [][]bool) Objective {
+	return func(found []Anomalies) float64 {
+		if len(found) != len(labels) {
+			panic("Labels and series of different lengths")
+		}
+		total := 0.0
+		for i, anomalies := range found {
+			truePositives, flagged, actual := 0, 0, 0
+			for j, p := range anomalies.Positions {
+				if p {
+					flagged++
+				}
+				if labels[i][j] {
+					actual++
+					if p {
+						truePositives++
+					}
+				}
+			}
+			if flagged+actual == 0 {
+				total++
+			} else {
+				total += 2 * float64(truePositives) / float64(flagged+actual)
+			}
+		}
+		return total / float64(len(found))
+	}
+}
+
+// RateObjective scores anomalies by how close the fraction of points flagged
+// across every series is to rate, for tuning metrics without labels towards
+// an acceptable alert volume.
+func RateObjective(rate float64) Objective {
+	return func(found []Anomalies) float64 {
+		flagged, total := 0, 0
+		for _, anomalies := range found {
+			for _, p := range anomalies.Positions {
+				if p {
+					flagged++
+				}
+			}
+			total += len(anomalies.Positions)
+		}
+		if total == 0 {
+			return 0
+		}
+		return -math.Abs(float64(flagged)/float64(total) - rate)
+	}
+}
+
+/*
+GridSearch runs FindAnomalies on every series with every combination of the
+values in the search space, and returns every combination with its score under
+the objective, best first. Combinations are tried concurrently, one per
+available CPU. The other options are applied to every combination before the
+tuned ones, and options that take a callback, such as OnIteration, have it
+called concurrently. Running the other options followed by the options of a
+trial's Params reproduces the trial.
+
+A combination that FindAnomalies rejects, such as a frequency that does not
+divide the length of a series unless the series are folded with an option such
+as PhaseOffset, fails with the reason in its Err. Failed trials and trials the
+objective scores NaN come last.
+*/
+func GridSearch(series [][]float64, space SearchSpace, objective Objective,
+	options ...func(*rpcaConfig) error) []Trial {
+	return runTrials(series, candidates(space, options), objective, options)
+}
+
+// RandomSearch is like GridSearch, but only tries up to trials of the
+// combinations, drawn at random from the search space without repeats, for
+// spaces too large to search exhaustively. The draw is determined by the seed.
+func RandomSearch(series [][]float64, space SearchSpace, objective Objective,
+	trials int, seed int64, options ...func(*rpcaConfig) error) []Trial {
+	params := candidates(space, options)
+	r := rand.New(rand.NewSource(seed))
+	r.Shuffle(len(params), func(a, b int) {
+		params[a], params[b] = params[b], params[a]
+	})
+	if trials < len(params) {
+		params = params[:max(trials, 0)]
+	}
+	return runTrials(series, params, objective, options)
+}
+
+// candidates returns every combination of the values in the search space,
+// filling empty dimensions from the options.
+func candidates(space SearchSpace, options []func(*rpcaConfig) error) []Params {
+	conf := configure(options)
+	frequencies := space.Frequencies
+	if len(frequencies) == 0 {
+		frequencies = []int{conf.frequency}
+	}
+	lPenalties := space.LPenalties
+	if len(lPenalties) == 0 {
+		lPenalties = []float64{conf.lPenalty}
+	}
+	sPenalties := space.SPenalties
+	if len(sPenalties) == 0 {
+		// Zero stands for the default, which depends on each series.
+		sPenalties = []float64{0}
+		if !math.IsNaN(conf.sPenalty) {
+			sPenalties = []float64{conf.sPenalty}
+		}
+	}
+	autoDiffs := space.AutoDiff
+	if len(autoDiffs) == 0 {
+		autoDiffs = []bool{conf.autodiff}
+	}
+	scales := space.Scale
+	if len(scales) == 0 {
+		scales = []bool{conf.scale}
+	}
+
+	var params []Params
+	for _, frequency := range frequencies {
+		if frequency <= 0 {
+			panic("Frequency less than or equal to zero")
+		}
+		for _, lPenalty := range lPenalties {
+			for _, sPenalty := range sPenalties {
+				for _, autoDiff := range autoDiffs {
+					for _, scale := range scales {
+						params = append(params, Params{
+							Frequency: frequency,
+							LPenalty:  lPenalty,
+							SPenalty:  sPenalty,
+							AutoDiff:  autoDiff,
+							Scale:     scale,
+						})
+					}
+				}
+			}
+		}
+	}
+	return params
+}
+
+// runTrials scores every combination of parameters concurrently and sorts
+// them.
+func runTrials(series [][]float64, params []Params, objective Objective,
+	options []func(*rpcaConfig) error) []Trial {
+	trials := make([]Trial, len(params))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				trials[i] = runTrial(series, params[i], objective, options)
+			}
+		}()
+	}
+	for i := range params {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	sortTrials(trials)
+	return trials
+}
+
+// sortTrials sorts trials best first, keeping the order of ties, with NaN
+// scores last.
+func sortTrials(trials []Trial) {
+	sort.SliceStable(trials, func(a, b int) bool {
+		if math.IsNaN(trials[b].Score) {
+			return !math.IsNaN(trials[a].Score)
+		}
+		return trials[a].Score > trials[b].Score
+	})
+}
+
+// runTrial scores one combination of parameters, recovering from any panic of
+// FindAnomalies or the objective as a failed trial.
+func runTrial(series [][]float64, params Params, objective Objective,
+	options []func(*rpcaConfig) error) (trial Trial) {
+	trial.Params = params
+	defer func() {
+		if r := recover(); r != nil {
+			trial.Score = math.NaN()
+			trial.Err = fmt.Errorf("%v", r)
+		}
+	}()
+	trialOptions := append(append([]func(*rpcaConfig) error(nil), options...),
+		params.Options()...)
+	found := make([]Anomalies, len(series))
+	for j, s := range series {
+		found[j] = FindAnomalies(s, trialOptions...)
+	}
+	trial.Score = objective(found)
+	return trial
+}
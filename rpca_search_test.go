@@ -0,0 +1,111 @@
+package rpca
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGridSearch(t *testing.T) {
+	series := [][]float64{seasonalSeries(56), seasonalSeries(56)}
+	series[0][30] += 20
+	series[1][12] -= 15
+	labels := [][]bool{make([]bool, 56), make([]bool, 56)}
+	labels[0][30], labels[1][12] = true, true
+
+	space := SearchSpace{
+		Frequencies: []int{7, 8},
+		SPenalties:  []float64{0, 0.01},
+	}
+	trials := GridSearch(series, space, LabelObjective(labels), AutoDiff(false))
+	if len(trials) != 4 {
+		t.Fatalf("Expected every combination to be tried but got %v", trials)
+	}
+	for i := 1; i < len(trials); i++ {
+		if trials[i].Score > trials[i-1].Score {
+			t.Errorf("Expected trials sorted best first but got %v", trials)
+		}
+	}
+	best := trials[0]
+	if best.Params.Frequency != 7 || best.Params.SPenalty != 0 || best.Params.AutoDiff {
+		t.Errorf("Expected the weekly frequency and default penalty to score "+
+			"best but got %+v", trials)
+	}
+	if !FindAnomalies(series[0], best.Params.Options()...).Positions[30] {
+		t.Errorf("Expected the best options to flag the labeled point")
+	}
+}
+
+func TestRandomSearch(t *testing.T) {
+	series := [][]float64{seasonalSeries(56)}
+	series[0][30] += 20
+	space := SearchSpace{
+		Frequencies: []int{7, 8, 14},
+		LPenalties:  []float64{0.5, 1, 2},
+		AutoDiff:    []bool{false},
+	}
+	trials := RandomSearch(series, space, RateObjective(1.0/56), 4, 1)
+	again := RandomSearch(series, space, RateObjective(1.0/56), 4, 1)
+	if len(trials) != 4 {
+		t.Fatalf("Expected 4 trials but got %v", trials)
+	}
+	seen := make(map[Params]bool)
+	for i, trial := range trials {
+		if seen[trial.Params] {
+			t.Errorf("Expected no repeated combinations but got %v", trials)
+		}
+		seen[trial.Params] = true
+		if trial != again[i] {
+			t.Errorf("Expected the same seed to draw the same trials but got "+
+				"%v and %v", trials, again)
+		}
+	}
+}
+
+func TestSearchFailedTrials(t *testing.T) {
+	series := [][]float64{seasonalSeries(56)}
+	series[0][30] += 20
+	space := SearchSpace{Frequencies: []int{9, 8, 7}}
+	objective := RateObjective(1.0 / 56)
+	trials := GridSearch(series, space, objective, AutoDiff(false), SPenalty(0.5))
+	if len(trials) != 3 {
+		t.Fatalf("Expected every combination to be tried but got %v", trials)
+	}
+	last := trials[len(trials)-1]
+	if last.Params.Frequency != 9 || last.Err == nil || !math.IsNaN(last.Score) {
+		t.Errorf("Expected the frequency of 9, which does not divide the series, "+
+			"to fail last but got %+v", trials)
+	}
+	for _, trial := range trials[:2] {
+		if trial.Err != nil || math.IsNaN(trial.Score) {
+			t.Errorf("Expected the other frequencies to succeed but got %+v", trial)
+		}
+		if trial.Params.SPenalty != 0.5 {
+			t.Errorf("Expected the S penalty of the options to be kept but got %v",
+				trial.Params.SPenalty)
+		}
+	}
+	best := trials[0]
+	anoms := FindAnomalies(series[0], append([]func(*rpcaConfig) error{AutoDiff(false)},
+		best.Params.Options()...)...)
+	if score := objective([]Anomalies{anoms}); score != best.Score {
+		t.Errorf("Expected the best options to reproduce a score of %v but got %v",
+			best.Score, score)
+	}
+}
+
+func TestSortTrials(t *testing.T) {
+	trials := []Trial{{Score: math.NaN()}, {Score: 1}, {Score: math.NaN()},
+		{Score: 3}, {Score: 2}}
+	sortTrials(trials)
+	for i, expected := range []float64{3, 2, 1} {
+		if trials[i].Score != expected {
+			t.Errorf("Expected trial %v to score %v but got %v", i, expected,
+				trials[i].Score)
+		}
+	}
+	for _, trial := range trials[3:] {
+		if !math.IsNaN(trial.Score) {
+			t.Errorf("Expected NaN scores last but got %v", trials)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package rpca
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/gonum/matrix/mat64"
+	"github.com/gonum/stat"
+)
+
+// computePValues scores every point's deviation from the low-rank fit, in the
+// normalized space, against a normal distribution of the noise component whose
+// scale is the median absolute deviation of the noise. With bootstrapping, the
+// p-values are averaged over the scales of the resampled noise instead, which
+// accounts for the uncertainty of the scale. Points that do not count towards
+// the fit, such as excluded ones, are left out of the noise and get a p-value
+// of one. The other p-values are Benjamini-Hochberg adjusted.
+func computePValues(decomp *decomposedMatrix, conf *rpcaConfig) []float64 {
+	deviations := decomp.unfold(decomp.SNormed)
+	noise := decomp.unfold(decomp.ENormed)
+	for i := range deviations {
+		deviations[i] = math.Abs(deviations[i] + noise[i])
+	}
+
+	scales := []float64{noiseSpread(decomp.weightedPoints(noise))}
+	if conf.bootstrap > 0 {
+		// Resample the periods of the folding, whatever its layout.
+		periods := buildMatrix(decomp.cells(decomp.ENormed), decomp.fold.rows)
+		scales = bootstrapScales(periods, decomp.weights, conf.bootstrap,
+			conf.seed)
+	}
+
+	pValues := make([]float64, len(deviations))
+	for i, d := range deviations {
+		for _, scale := range scales {
+			pValues[i] += tailPValue(d, scale) / float64(len(scales))
+		}
+	}
+	weighted := decomp.weighted()
+	if weighted == nil {
+		return adjustBH(pValues)
+	}
+	var tested []float64
+	for i, p := range pValues {
+		if weighted[i] {
+			tested = append(tested, p)
+		}
+	}
+	adjusted := adjustBH(tested)
+	for i := range pValues {
+		pValues[i] = 1
+		if weighted[i] {
+			pValues[i], adjusted = adjusted[0], adjusted[1:]
+		}
+	}
+	return pValues
+}
+
+// noiseSpread estimates the standard deviation of the noise from its median
+// absolute deviation, which the few large residuals left at anomalies do not
+// inflate. It falls back to the standard deviation when most of the noise is
+// zero.
+func noiseSpread(noise []float64) float64 {
+	_, scale := medianAbsoluteDeviation(noise)
+	if scale == 0 {
+		scale = stat.StdDev(noise, nil)
+	}
+	return scale
+}
+
+// bootstrapScales estimates the scale of the noise once for every resample of
+// whole columns (seasonal periods) of the noise matrix drawn with replacement.
+// Cells with a weight of zero are left out if weights is not nil.
+func bootstrapScales(e mat64.Matrix, weights *mat64.Dense, resamples int,
+	seed int64) []float64 {
+	rows, cols := e.Dims()
+	rng := rand.New(rand.NewSource(seed))
+	scales := make([]float64, resamples)
+	for b := range scales {
+		resample := make([]float64, 0, rows*cols)
+		for j := 0; j < cols; j++ {
+			col := rng.Intn(cols)
+			for i := 0; i < rows; i++ {
+				if weights == nil || weights.At(i, col) != 0 {
+					resample = append(resample, e.At(i, col))
+				}
+			}
+		}
+		scales[b] = noiseSpread(resample)
+	}
+	return scales
+}
+
+// tailPValue returns the probability that normal noise of the given scale
+// deviates from zero by at least d in either direction. Without noise, only
+// a deviation of zero is likely.
+func tailPValue(d, scale float64) float64 {
+	if scale == 0 {
+		if d == 0 {
+			return 1
+		}
+		return 0
+	}
+	return math.Erfc(d / (scale * math.Sqrt2))
+}
+
+// adjustBH applies the Benjamini-Hochberg step-up procedure to a slice of
+// p-values, returning adjusted p-values in the original order.
+func adjustBH(pValues []float64) []float64 {
+	m := len(pValues)
+	order := make([]int, m)
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return pValues[order[a]] < pValues[order[b]]
+	})
+	adjusted := make([]float64, m)
+	running := 1.0
+	for k := m - 1; k >= 0; k-- {
+		i := order[k]
+		running = math.Min(running, pValues[i]*float64(m)/float64(k+1))
+		adjusted[i] = running
+	}
+	return adjusted
+}
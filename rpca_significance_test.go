@@ -0,0 +1,96 @@
+package rpca
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAdjustBH(t *testing.T) {
+	pValues := []float64{0.01, 0.04, 0.03, 0.005}
+	expected := []float64{0.02, 0.04, 0.04, 0.02}
+	observed := adjustBH(pValues)
+	for i := range expected {
+		if math.Abs(observed[i]-expected[i]) > 1e-12 {
+			t.Errorf("Failed adjusting p-value %v. Expected %v but got %v",
+				i, expected[i], observed[i])
+		}
+	}
+}
+
+func TestPValues(t *testing.T) {
+	series := make([]float64, 56)
+	for i := range series {
+		series[i] = 10 + 5*math.Sin(2*math.Pi*float64(i)/7) + math.Cos(float64(i*i))
+	}
+	series[30] += 100
+	for _, resamples := range []int{0, 20} {
+		anoms := FindAnomalies(series, AutoDiff(false), PValues(true),
+			Bootstrap(resamples))
+		if len(anoms.PValues) != len(series) {
+			t.Fatalf("Expected %v p-values but got %v", len(series), len(anoms.PValues))
+		}
+		largest := 0.0
+		for i, p := range anoms.PValues {
+			if p < 0 || p > 1 {
+				t.Errorf("P-value %v out of range: %v", i, p)
+			}
+			largest = math.Max(largest, p)
+			if p < anoms.PValues[30] {
+				t.Errorf("Expected spike to have the smallest p-value, but point %v "+
+					"had %v against %v", i, p, anoms.PValues[30])
+			}
+		}
+		if anoms.PValues[30] >= largest {
+			t.Errorf("Expected spike p-value %v to be below the largest p-value %v",
+				anoms.PValues[30], largest)
+		}
+	}
+}
+
+func TestBootstrapWithPhaseOffset(t *testing.T) {
+	// 59 points starting on the fourth day of the week fold into nine partly
+	// filled weeks, so the periods resampled are the fold's columns rather
+	// than runs of seven points from the start of the series.
+	series := make([]float64, 59)
+	for i := range series {
+		series[i] = 10 + 5*math.Sin(2*math.Pi*float64(i+3)/7) + math.Cos(float64(i*i))
+	}
+	series[30] += 100
+	anoms := FindAnomalies(series, AutoDiff(false), PhaseOffset(3), PValues(true),
+		Bootstrap(20))
+	if len(anoms.PValues) != len(series) {
+		t.Fatalf("Expected %v p-values but got %v", len(series), len(anoms.PValues))
+	}
+	for i, p := range anoms.PValues {
+		if p < 0 || p > 1 {
+			t.Errorf("P-value %v out of range: %v", i, p)
+		}
+		if i != 30 && p < anoms.PValues[30] {
+			t.Errorf("Expected spike to have the smallest p-value, but point %v "+
+				"had %v against %v", i, p, anoms.PValues[30])
+		}
+	}
+}
+
+func TestPValuesOfSpike(t *testing.T) {
+	series := make([]float64, 56)
+	for i := range series {
+		series[i] = 10 + 5*math.Sin(2*math.Pi*float64(i)/7) + math.Cos(float64(i*i))
+	}
+	series[30] += 10
+	var spike []float64
+	for _, resamples := range []int{0, 20, 1000} {
+		anoms := FindAnomalies(series, AutoDiff(false), PValues(true),
+			Bootstrap(resamples))
+		spike = append(spike, anoms.PValues[30])
+		if anoms.PValues[30] >= 0.05 {
+			t.Errorf("Expected the spike to be significant with %v resamples, but "+
+				"got a p-value of %v", resamples, anoms.PValues[30])
+		}
+	}
+	// More resamples refine the p-value rather than shrink it.
+	if spike[2] < spike[1]/10 || spike[2] > spike[1]*10 {
+		t.Errorf("Expected 20 and 1000 resamples to give similar p-values, but "+
+			"got %v and %v", spike[1], spike[2])
+	}
+}
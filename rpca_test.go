@@ -48,3 +48,17 @@ func BenchmarkComputeRPCA(b *testing.B) {
 		computeRPCA(testCase.timeSeries, testCase.options)
 	}
 }
+
+func TestFindAnomaliesDifferencedPositions(t *testing.T) {
+	series := seasonalSeries(56)
+	series[30] += 50
+	anoms := FindAnomalies(series, AutoDiff(false), ForceDiff(true))
+	if !anoms.Positions[30] || anoms.Values[30] <= 0 {
+		t.Errorf("Expected spike at 30 to be anomalously high, but got %v",
+			anoms.Values[30])
+	}
+	if !anoms.Positions[31] || anoms.Values[31] >= 0 {
+		t.Errorf("Expected drop back at 31 to be anomalously low, but got %v",
+			anoms.Values[31])
+	}
+}
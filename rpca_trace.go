@@ -0,0 +1,83 @@
+package rpca
+
+import (
+	"log/slog"
+	"os"
+)
+
+// IterationStats describes the state of the solver at the end of a single
+// iteration of the algorithm.
+type IterationStats struct {
+	// The zero-based number of the iteration.
+	Iteration int
+
+	// The convergence rate used during the iteration, and the penalties it
+	// produced for the low-rank (L) and sparse (S) components.
+	Mu, LPenalty, SPenalty float64
+
+	// The penalized norms of the low-rank (L), sparse (S) and noise (E)
+	// components, which together make up the objective.
+	LNorm, SNorm, ENorm float64
+
+	// The value of the objective function, and how much it changed since the
+	// previous iteration. The algorithm stops once the change is small enough.
+	// The InexactALM solver has no noise term in its objective and instead
+	// reports how far L + S is from the data, relative to the size of the data.
+	Objective, Difference float64
+
+	// The rank of the low-rank component (L), that is, the number of singular
+	// values that survived thresholding.
+	Rank int
+
+	// The fraction of points in the sparse component (S) that are non-zero,
+	// and so currently considered anomalous.
+	Sparsity float64
+}
+
+func (stats IterationStats) attrs() []any {
+	return []any{
+		"iteration", stats.Iteration,
+		"mu", stats.Mu,
+		"lPenalty", stats.LPenalty,
+		"sPenalty", stats.SPenalty,
+		"lNorm", stats.LNorm,
+		"sNorm", stats.SNorm,
+		"eNorm", stats.ENorm,
+		"objective", stats.Objective,
+		"difference", stats.Difference,
+		"rank", stats.Rank,
+		"sparsity", stats.Sparsity,
+	}
+}
+
+// log returns the logger the algorithm should report its progress to, or nil
+// if progress should not be reported.
+func (conf *rpcaConfig) log() *slog.Logger {
+	if conf.logger != nil {
+		return conf.logger
+	}
+	if conf.verbose {
+		return slog.New(slog.NewTextHandler(os.Stderr,
+			&slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+	return nil
+}
+
+// tag marks everything the configuration logs as belonging to the named
+// series, for functions that decompose several.
+func (conf *rpcaConfig) tag(series string) {
+	if logger := conf.log(); logger != nil {
+		conf.logger = logger.With("series", series)
+	}
+}
+
+// report hands the stats of a finished iteration to the user's callback and
+// logger, if any.
+func (conf *rpcaConfig) report(stats IterationStats, logger *slog.Logger) {
+	if conf.onIteration != nil {
+		conf.onIteration(stats)
+	}
+	if logger != nil {
+		logger.Debug("rpca: iteration", stats.attrs()...)
+	}
+}
@@ -0,0 +1,40 @@
+package rpca
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestOnIteration(t *testing.T) {
+	var trace []IterationStats
+	FindAnomalies(seasonalSeries(56), AutoDiff(false),
+		OnIteration(func(stats IterationStats) {
+			trace = append(trace, stats)
+		}))
+	if len(trace) == 0 {
+		t.Fatal("Expected at least one iteration to be reported")
+	}
+	for i, stats := range trace {
+		if stats.Iteration != i {
+			t.Errorf("Failed on iteration number. Expected %v but got %v",
+				i, stats.Iteration)
+		}
+		objective := computeObjective(stats.LNorm, stats.SNorm, stats.ENorm)
+		if stats.Objective != objective {
+			t.Errorf("Failed on iteration %v objective. Expected %v but got %v",
+				i, objective, stats.Objective)
+		}
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf,
+		&slog.HandlerOptions{Level: slog.LevelDebug}))
+	FindAnomalies(seasonalSeries(56), AutoDiff(false), Logger(logger))
+	if !strings.Contains(buf.String(), "rpca: iteration") {
+		t.Errorf("Expected iterations to be logged, but got:\n%v", buf.String())
+	}
+}
@@ -1,8 +1,10 @@
 package rpca
 
 import (
+	"github.com/gonum/matrix"
 	"github.com/gonum/matrix/mat64"
 	"math"
+	"sort"
 )
 
 func signum(f float64) float64 {
@@ -39,6 +41,24 @@ func sum(vals []float64) float64 {
 	return sum
 }
 
+func sumSquares(vals []float64) float64 {
+	sum := 0.0
+	for _, v := range vals {
+		sum += v * v
+	}
+	return sum
+}
+
+// l21Norm returns the sum of the L2 norms of the columns of the matrix.
+func l21Norm(mat mat64.Matrix) float64 {
+	_, c := mat.Dims()
+	sum := 0.0
+	for j := 0; j < c; j++ {
+		sum += math.Sqrt(sumSquares(mat64.Col(nil, j, mat)))
+	}
+	return sum
+}
+
 func l1Norm(mat mat64.RawMatrixer) float64 {
 	sum := 0.0
 	for _, v := range mat.RawMatrix().Data {
@@ -47,6 +67,118 @@ func l1Norm(mat mat64.RawMatrixer) float64 {
 	return sum
 }
 
+// spectralNorm returns the largest singular value of the matrix.
+func spectralNorm(mat mat64.Matrix) float64 {
+	var svd mat64.SVD
+	svd.Factorize(mat, matrix.SVDNone)
+	return svd.Values(nil)[0]
+}
+
+func maxAbs(mat mat64.RawMatrixer) float64 {
+	max := 0.0
+	for _, v := range mat.RawMatrix().Data {
+		max = math.Max(max, math.Abs(v))
+	}
+	return max
+}
+
+// projectRank returns the best approximation of the matrix with rank at most k,
+// along with all of the matrix's singular values.
+func projectRank(mat mat64.Matrix, k int) (*mat64.Dense, []float64) {
+	var svd mat64.SVD
+	svd.Factorize(mat, matrix.SVDThin)
+	values := svd.Values(nil)
+	kept := make([]float64, len(values))
+	copy(kept, values[:int(math.Min(float64(k), float64(len(values))))])
+	diag := mat64.NewDense(len(kept), len(kept), nil)
+	setDiag(diag, kept)
+	var u, v, ud, l mat64.Dense
+	u.UFromSVD(&svd)
+	v.VFromSVD(&svd)
+	ud.Mul(&u, diag)
+	l.Mul(&ud, v.T())
+	return &l, values
+}
+
+// hardThreshold returns a copy of the matrix with every value whose magnitude
+// is not above the threshold set to zero.
+func hardThreshold(mat mat64.Matrix, threshold float64) *mat64.Dense {
+	r, c := mat.Dims()
+	thresholded := mat64.NewDense(r, c, nil)
+	thresholded.Apply(func(i, j int, v float64) float64 {
+		if math.Abs(v) > threshold {
+			return v
+		}
+		return 0
+	}, mat)
+	return thresholded
+}
+
+// keepLargest zeroes all but the count values of largest magnitude in the
+// matrix, in place.
+func keepLargest(mat *mat64.Dense, count int) {
+	data := mat.RawMatrix().Data
+	if count >= len(data) {
+		return
+	}
+	order := make([]int, len(data))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return math.Abs(data[order[a]]) > math.Abs(data[order[b]])
+	})
+	for _, i := range order[int(math.Max(0, float64(count))):] {
+		data[i] = 0
+	}
+}
+
+// sparsity returns the fraction of non-zero values in the matrix.
+func sparsity(mat mat64.RawMatrixer) float64 {
+	data := mat.RawMatrix().Data
+	nonzero := 0
+	for _, v := range data {
+		if v != 0 {
+			nonzero++
+		}
+	}
+	return float64(nonzero) / float64(len(data))
+}
+
+// normalizeWeights checks that there is a non-negative weight for each of the
+// n points of a series and scales them so the largest weight is one.
+func normalizeWeights(weights []float64, n int) []float64 {
+	if len(weights) != n {
+		panic("Number of weights not equal to length of time series")
+	}
+	max := 0.0
+	for _, w := range weights {
+		if w < 0 {
+			panic("Weight less than zero")
+		}
+		max = math.Max(max, w)
+	}
+	if max == 0 {
+		panic("All weights equal to zero")
+	}
+	normalized := make([]float64, n)
+	for i, w := range weights {
+		normalized[i] = w / max
+	}
+	return normalized
+}
+
+// diffWeights returns the weights of a differenced series: each change is only
+// as reliable as the less reliable of its two points, and the zero that pads
+// the differenced series carries no weight at all.
+func diffWeights(weights []float64) []float64 {
+	diffed := make([]float64, len(weights))
+	for i := 1; i < len(weights); i++ {
+		diffed[i] = math.Min(weights[i-1], weights[i])
+	}
+	return diffed
+}
+
 func buildMatrix(series []float64, frequency int) rPCAable {
 	lenSeries := len(series)
 	if frequency <= 0 {
@@ -77,3 +209,13 @@ func diff(x []float64) []float64 {
 	}
 	return y
 }
+
+// median returns the median of values, which it sorts.
+func median(values []float64) float64 {
+	sort.Float64s(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
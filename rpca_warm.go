@@ -0,0 +1,82 @@
+package rpca
+
+import "github.com/gonum/matrix/mat64"
+
+/*
+State carries a decomposition from one run to the next, for the WarmStart
+option. The zero value is ready to use and makes the first run start cold.
+A State must not be shared between runs that may happen at the same time.
+*/
+type State struct {
+	// The low-rank component of the last run, in the order of the points of
+	// its series and in its domain (differenced if it was, but not scaled).
+	l []float64
+
+	// The convergence rate the last run would have continued with.
+	mu float64
+
+	differenced bool
+}
+
+// start is where a solver starts from: the low-rank component of the prepared
+// matrix, and the convergence rate. The Surus solver computes the sparse
+// component from the low-rank one first, so it needs no starting point.
+type start struct {
+	l  *mat64.Dense
+	mu float64
+}
+
+/*
+warmStart builds the starting point of the solver from the state of the last
+run, shifted by the configured number of points and prepared the same way as
+the matrix: differenced if differenced and scaled by the given mean and
+standard deviation. Points past the end of the last run take the low-rank
+value of the point a period before them in the folding, such as the same day
+of the previous month with the Monthly option. It returns
+nil to start cold if there is no state, if the last run was differenced and
+this one is not or vice versa, or if the series moved past every point of the
+last run.
+*/
+func (conf *rpcaConfig) warmStart(fold *folding, differenced bool,
+	mean, stdDev float64) *start {
+	state := conf.state
+	if state == nil || state.l == nil || state.differenced != differenced ||
+		conf.stateShift >= len(state.l) {
+		return nil
+	}
+	l := shiftPoints(state.l, conf.stateShift, fold)
+	for i := range l {
+		l[i] = (l[i] - mean) / stdDev
+	}
+	if differenced {
+		// The first value only pads the differenced series.
+		l[0] = 0
+	}
+	return &start{l: fold.fold(l).(*mat64.Dense), mu: state.mu}
+}
+
+// shiftPoints drops the first shift values and extends the rest to the points
+// of the folding. Every new point takes the value of the last point before it
+// in the same row of the folding, a period before it, or zero if there is none.
+func shiftPoints(values []float64, shift int, fold *folding) []float64 {
+	shifted := make([]float64, len(fold.cells))
+	copy(shifted, values[shift:])
+	for i := len(values) - shift; i < len(shifted); i++ {
+		shifted[i], _ = fold.lastInRow(shifted, fold.cells[i]%fold.rows, i)
+	}
+	return shifted
+}
+
+// saveState records the low-rank component the solver found for the prepared
+// matrix, scaled back by the given mean and standard deviation, and the
+// convergence rate it would have continued with, for the next run.
+func (conf *rpcaConfig) saveState(fold *folding, differenced bool, sol solution,
+	weights *mat64.Dense, mean, stdDev float64) {
+	state := conf.state
+	state.l = fold.unfold(matrixData(sol.l))
+	for i := range state.l {
+		state.l[i] = state.l[i]*stdDev + mean
+	}
+	state.mu = computeDynamicMu(sol.e, weights)
+	state.differenced = differenced
+}
@@ -0,0 +1,71 @@
+package rpca
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWarmStart(t *testing.T) {
+	series := make([]float64, 24*30)
+	for i := range series {
+		series[i] = 10 + 5*math.Sin(2*math.Pi*float64(i)/24) + 0.3*math.Cos(float64(i*i))
+	}
+	series[24*10+5] += 15
+	window := 24 * 14
+
+	for _, shift := range []int{1, 24} {
+		state := &State{}
+		FindAnomalies(series[:window], Frequency(24), WarmStart(state, 0))
+		next := series[shift : window+shift]
+		cold := FindAnomalies(next, Frequency(24), CollectDiagnostics(true))
+		warm := FindAnomalies(next, Frequency(24), WarmStart(state, shift),
+			CollectDiagnostics(true))
+		if !warm.Diagnostics.Converged ||
+			2*len(warm.Diagnostics.Iterations) > len(cold.Diagnostics.Iterations) {
+			t.Errorf("Expected a warm start shifted by %v to need at most half "+
+				"the %v iterations of a cold start but got %v", shift,
+				len(cold.Diagnostics.Iterations), len(warm.Diagnostics.Iterations))
+		}
+		for i := range cold.Positions {
+			if cold.Positions[i] != warm.Positions[i] {
+				t.Errorf("Expected a warm start shifted by %v to agree with a cold "+
+					"start on point %v", shift, i)
+			}
+		}
+		if !warm.Positions[24*10+5-shift] {
+			t.Errorf("Expected a warm start shifted by %v to find the spike", shift)
+		}
+	}
+}
+
+func TestShiftPoints(t *testing.T) {
+	shifted := shiftPoints([]float64{1, 2, 3, 4}, 1, regularFolding(6, 2, 0))
+	expected := []float64{2, 3, 4, 3, 4, 3}
+	for i := range expected {
+		if shifted[i] != expected[i] {
+			t.Fatalf("Expected %v but got %v", expected, shifted)
+		}
+	}
+}
+
+func TestWarmStartRejects(t *testing.T) {
+	tests := []struct {
+		description string
+		options     []func(*rpcaConfig) error
+	}{
+		{"nil state", []func(*rpcaConfig) error{WarmStart(nil, 0)}},
+		{"negative shift", []func(*rpcaConfig) error{WarmStart(&State{}, -1)}},
+		{"other solver", []func(*rpcaConfig) error{WarmStart(&State{}, 0),
+			Solver(InexactALM)}},
+	}
+	for _, test := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected '%v' to panic", test.description)
+				}
+			}()
+			FindAnomalies(seasonalSeries(56), test.options...)
+		}()
+	}
+}
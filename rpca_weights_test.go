@@ -0,0 +1,38 @@
+package rpca
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWeights(t *testing.T) {
+	for _, diff := range []bool{false, true} {
+		series := seasonalSeries(56)
+		series[30] += 50
+		series[40] += 50
+		weights := make([]float64, len(series))
+		for i := range weights {
+			weights[i] = 2
+		}
+		weights[30] = 0
+		conf := newConfig(series, []func(*rpcaConfig) error{
+			AutoDiff(false), ForceDiff(diff), Weights(weights)})
+		decomposed := computeRPCA(buildMatrix(series, 7), &conf)
+		anoms := decomposedToAnomalies(&decomposed)
+		if anoms.Positions[30] {
+			t.Errorf("Expected point with zero weight not to be flagged with "+
+				"differencing %v", diff)
+		}
+		if !anoms.Positions[40] {
+			t.Errorf("Expected spike at 40 to be flagged with differencing %v", diff)
+		}
+		if !diff {
+			l := decomposed.unfold(decomposed.L)
+			expected := 10 + 5*math.Sin(2*math.Pi*30/7)
+			if math.Abs(l[30]-expected) > 1 {
+				t.Errorf("Expected point with zero weight not to pull on the low-rank "+
+					"fit. Expected about %v but got %v", expected, l[30])
+			}
+		}
+	}
+}
@@ -0,0 +1,396 @@
+/*
+Package synth generates time series with known anomalies, for testing anomaly
+detection and the systems built around it. A series is the sum of a level, a
+trend, any number of seasonal cycles and noise, into which spikes, dips, level
+shifts and changes in variance are injected at random points. The labels of
+the injected anomalies are returned alongside the values, and the same seed
+always gives the same series:
+
+	s := synth.Generate(28*24,
+		synth.Seasonality(24, 10),
+		synth.Seasonality(7*24, 5),
+		synth.Noise(synth.Normal, 1),
+		synth.Spikes(3, 8),
+		synth.Seed(42))
+	anomalies := rpca.FindAnomalies(s.Values, rpca.Frequency(24))
+*/
+package synth
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// Distribution is the distribution of the noise added to a series.
+type Distribution int
+
+const (
+	// Normal noise has the given standard deviation.
+	Normal Distribution = iota
+
+	// Laplace noise has heavier tails than normal noise with the same
+	// standard deviation.
+	Laplace
+
+	// StudentT noise follows a Student's t distribution with three degrees of
+	// freedom, scaled to the given standard deviation, whose tails are heavy
+	// enough to produce occasional outliers that are not labeled.
+	StudentT
+
+	// Uniform noise is spread evenly over an interval with the given
+	// standard deviation.
+	Uniform
+)
+
+// Kind is the kind of an injected anomaly.
+type Kind int
+
+const (
+	// Spike adds the magnitude to a single point.
+	Spike Kind = iota
+
+	// Dip subtracts the magnitude from a single point.
+	Dip
+
+	// LevelShift adds the magnitude to a point and every point after it.
+	LevelShift
+
+	// VarianceChange multiplies the noise of a stretch of points by the
+	// magnitude.
+	VarianceChange
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Spike:
+		return "spike"
+	case Dip:
+		return "dip"
+	case LevelShift:
+		return "level shift"
+	case VarianceChange:
+		return "variance change"
+	default:
+		return "unknown"
+	}
+}
+
+// Anomaly is an anomaly injected into a series, covering the points from
+// Start up to but not including End. A level shift covers only the point
+// where the level changes.
+type Anomaly struct {
+	Kind       Kind
+	Start, End int
+	Magnitude  float64
+}
+
+type Series struct {
+	// Values holds the generated series. Missing points are NaN.
+	Values []float64
+
+	// Timestamps holds the time of every point.
+	Timestamps []time.Time
+
+	// Labels holds whether every point is covered by an injected anomaly.
+	Labels []bool
+
+	// Missing holds whether every point is missing.
+	Missing []bool
+
+	// Anomalies holds the injected anomalies, ordered by Start.
+	Anomalies []Anomaly
+}
+
+// Present returns copies of the values, timestamps and labels with the missing
+// points left out. The series itself keeps every point, with missing ones set
+// to NaN. Pass the timestamps to rpca with both the Timestamps and Location
+// options, which fold by time and fill in the gaps; folded by counting points,
+// the shortened series no longer lines up with its period.
+func (s Series) Present() ([]float64, []time.Time, []bool) {
+	var values []float64
+	var timestamps []time.Time
+	var labels []bool
+	for i, missing := range s.Missing {
+		if !missing {
+			values = append(values, s.Values[i])
+			timestamps = append(timestamps, s.Timestamps[i])
+			labels = append(labels, s.Labels[i])
+		}
+	}
+	return values, timestamps, labels
+}
+
+type seasonality struct {
+	period, amplitude float64
+}
+
+type injection struct {
+	kind      Kind
+	count     int
+	magnitude float64
+	length    int
+}
+
+type synthConfig struct {
+	level, trend float64
+	seasons      []seasonality
+	distribution Distribution
+	noise        float64
+	missing      float64
+	injections   []injection
+	start        time.Time
+	interval     time.Duration
+	seed         int64
+}
+
+// Level sets the value the series starts from. The default is 100.
+func Level(level float64) func(*synthConfig) error {
+	return func(conf *synthConfig) error {
+		conf.level = level
+		return nil
+	}
+}
+
+// Trend adds slope to the series at every point.
+func Trend(slope float64) func(*synthConfig) error {
+	return func(conf *synthConfig) error {
+		conf.trend = slope
+		return nil
+	}
+}
+
+// Seasonality adds a sine wave with the given period, in points, and
+// amplitude. It may be given several times for series with several cycles,
+// such as daily and weekly ones.
+func Seasonality(period int, amplitude float64) func(*synthConfig) error {
+	return func(conf *synthConfig) error {
+		if period <= 0 {
+			return errors.New("period less than or equal to zero")
+		}
+		conf.seasons = append(conf.seasons, seasonality{float64(period), amplitude})
+		return nil
+	}
+}
+
+// Noise adds noise with the given distribution and standard deviation to every
+// point. The default is normal noise with a standard deviation of 1.
+func Noise(distribution Distribution, stddev float64) func(*synthConfig) error {
+	return func(conf *synthConfig) error {
+		if stddev < 0 {
+			return errors.New("negative standard deviation")
+		}
+		conf.distribution = distribution
+		conf.noise = stddev
+		return nil
+	}
+}
+
+// Missing marks the given fraction of the points as missing and sets them to
+// NaN. Missing points are never covered by an injected anomaly, so if the
+// anomalies leave fewer points than that, only those are missing.
+func Missing(fraction float64) func(*synthConfig) error {
+	return func(conf *synthConfig) error {
+		if fraction < 0 || fraction >= 1 {
+			return errors.New("missing fraction outside of [0, 1)")
+		}
+		conf.missing = fraction
+		return nil
+	}
+}
+
+// Spikes injects count spikes of the given magnitude.
+func Spikes(count int, magnitude float64) func(*synthConfig) error {
+	return inject(injection{kind: Spike, count: count, magnitude: magnitude})
+}
+
+// Dips injects count dips of the given magnitude.
+func Dips(count int, magnitude float64) func(*synthConfig) error {
+	return inject(injection{kind: Dip, count: count, magnitude: magnitude})
+}
+
+// LevelShifts injects count level shifts of the given magnitude, which may be
+// negative to shift the level down.
+func LevelShifts(count int, magnitude float64) func(*synthConfig) error {
+	return inject(injection{kind: LevelShift, count: count, magnitude: magnitude})
+}
+
+// VarianceChanges injects count stretches of length points whose noise is
+// multiplied by factor.
+func VarianceChanges(count, length int, factor float64) func(*synthConfig) error {
+	inj := inject(injection{kind: VarianceChange, count: count, magnitude: factor,
+		length: length})
+	return func(conf *synthConfig) error {
+		if length <= 0 {
+			return errors.New("length less than or equal to zero")
+		}
+		return inj(conf)
+	}
+}
+
+func inject(inj injection) func(*synthConfig) error {
+	return func(conf *synthConfig) error {
+		if inj.count < 0 {
+			return errors.New("negative anomaly count")
+		}
+		conf.injections = append(conf.injections, inj)
+		return nil
+	}
+}
+
+// Start sets the time of the first point and the interval between points. The
+// default is hourly points from the start of 2016 in UTC.
+func Start(start time.Time, interval time.Duration) func(*synthConfig) error {
+	return func(conf *synthConfig) error {
+		if interval <= 0 {
+			return errors.New("interval less than or equal to zero")
+		}
+		conf.start = start
+		conf.interval = interval
+		return nil
+	}
+}
+
+// Seed sets the seed of the random numbers behind the noise, the placement of
+// anomalies and missing points. The default is 1.
+func Seed(seed int64) func(*synthConfig) error {
+	return func(conf *synthConfig) error {
+		conf.seed = seed
+		return nil
+	}
+}
+
+/*
+Generate generates a series of n points. Anomalies are placed at random, never
+on the first point and never overlapping one another, in the order their
+options were given. It panics if they do not fit in the series, or with the
+error of any option that rejects its arguments.
+*/
+func Generate(n int, options ...func(*synthConfig) error) Series {
+	conf := synthConfig{
+		level:    100,
+		noise:    1,
+		start:    time.Date(2016, time.January, 1, 0, 0, 0, 0, time.UTC),
+		interval: time.Hour,
+		seed:     1,
+	}
+	for _, option := range options {
+		if err := option(&conf); err != nil {
+			panic(err)
+		}
+	}
+	r := rand.New(rand.NewSource(conf.seed))
+
+	s := Series{
+		Values:     make([]float64, n),
+		Timestamps: make([]time.Time, n),
+		Labels:     make([]bool, n),
+		Missing:    make([]bool, n),
+	}
+	scales := make([]float64, n)
+	for i := range scales {
+		scales[i] = 1
+	}
+	shifts := make([]float64, n)
+	for _, inj := range conf.injections {
+		length := max(inj.length, 1)
+		for c := 0; c < inj.count; c++ {
+			start := place(r, s.Labels, length)
+			anomaly := Anomaly{Kind: inj.kind, Start: start, End: start + length,
+				Magnitude: inj.magnitude}
+			for i := start; i < start+length; i++ {
+				s.Labels[i] = true
+				switch inj.kind {
+				case Spike:
+					shifts[i] += inj.magnitude
+				case Dip:
+					shifts[i] -= inj.magnitude
+				case VarianceChange:
+					scales[i] *= inj.magnitude
+				}
+			}
+			if inj.kind == LevelShift {
+				for i := start; i < n; i++ {
+					shifts[i] += inj.magnitude
+				}
+			}
+			s.Anomalies = append(s.Anomalies, anomaly)
+		}
+	}
+	sort.Slice(s.Anomalies, func(a, b int) bool {
+		return s.Anomalies[a].Start < s.Anomalies[b].Start
+	})
+
+	for i := range s.Values {
+		v := conf.level + conf.trend*float64(i) + shifts[i]
+		for _, season := range conf.seasons {
+			v += season.amplitude * math.Sin(2*math.Pi*float64(i)/season.period)
+		}
+		s.Values[i] = v + scales[i]*conf.noise*noise(r, conf.distribution)
+		s.Timestamps[i] = conf.start.Add(time.Duration(i) * conf.interval)
+	}
+
+	var free []int
+	for i, label := range s.Labels {
+		if !label {
+			free = append(free, i)
+		}
+	}
+	r.Shuffle(len(free), func(a, b int) { free[a], free[b] = free[b], free[a] })
+	for _, i := range free[:min(int(conf.missing*float64(n)), len(free))] {
+		s.Missing[i] = true
+		s.Values[i] = math.NaN()
+	}
+	return s
+}
+
+// place returns the start of a random stretch of length points, after the
+// first, that are not yet labeled, and whose neighbours are not labeled either
+// so that anomalies stay distinct.
+func place(r *rand.Rand, labels []bool, length int) int {
+	var starts []int
+	for start := 1; start+length <= len(labels); start++ {
+		free := true
+		for i := start - 1; i <= start+length && i < len(labels); i++ {
+			if labels[i] {
+				free = false
+				break
+			}
+		}
+		if free {
+			starts = append(starts, start)
+		}
+	}
+	if len(starts) == 0 {
+		panic("Not enough room in the series for the anomalies")
+	}
+	return starts[r.Intn(len(starts))]
+}
+
+// noise draws from the distribution with a mean of zero and a standard
+// deviation of one.
+func noise(r *rand.Rand, distribution Distribution) float64 {
+	switch distribution {
+	case Laplace:
+		// An exponential draw with a random sign, whose variance is two.
+		if r.Intn(2) == 0 {
+			return -r.ExpFloat64() / math.Sqrt2
+		}
+		return r.ExpFloat64() / math.Sqrt2
+	case StudentT:
+		// Normal over the root of a scaled chi-squared with three degrees of
+		// freedom, whose variance is three.
+		chi := 0.0
+		for k := 0; k < 3; k++ {
+			z := r.NormFloat64()
+			chi += z * z
+		}
+		return r.NormFloat64() / math.Sqrt(chi/3) / math.Sqrt(3)
+	case Uniform:
+		return (2*r.Float64() - 1) * math.Sqrt(3)
+	default:
+		return r.NormFloat64()
+	}
+}
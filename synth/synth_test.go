@@ -0,0 +1,159 @@
+package synth
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/berkmancenter/rpca"
+)
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	options := []func(*synthConfig) error{
+		Seasonality(24, 10), Noise(Laplace, 2), Spikes(2, 20), Missing(0.1), Seed(7),
+	}
+	a, b := Generate(240, options...), Generate(240, options...)
+	if !reflect.DeepEqual(a.Anomalies, b.Anomalies) ||
+		!reflect.DeepEqual(a.Missing, b.Missing) {
+		t.Errorf("Expected the same seed to give the same series")
+	}
+	for i := range a.Values {
+		if a.Values[i] != b.Values[i] && !(a.Missing[i] && b.Missing[i]) {
+			t.Errorf("Expected the same value at point %v but got %v and %v",
+				i, a.Values[i], b.Values[i])
+		}
+	}
+	if c := Generate(240, append(options, Seed(8))...); reflect.DeepEqual(a.Anomalies, c.Anomalies) {
+		t.Errorf("Expected another seed to place the anomalies elsewhere")
+	}
+}
+
+func TestGenerateLabels(t *testing.T) {
+	s := Generate(200,
+		Noise(Normal, 0),
+		Trend(0.5),
+		Spikes(2, 10),
+		Dips(1, 10),
+		LevelShifts(1, 30),
+		VarianceChanges(1, 5, 3),
+		Missing(0.2))
+	if len(s.Anomalies) != 5 {
+		t.Fatalf("Expected 5 anomalies but got %v", s.Anomalies)
+	}
+	labeled, missing := 0, 0
+	for i := range s.Values {
+		if s.Labels[i] {
+			labeled++
+		}
+		if s.Missing[i] {
+			missing++
+			if s.Labels[i] || !math.IsNaN(s.Values[i]) {
+				t.Errorf("Expected missing point %v to be NaN and unlabeled", i)
+			}
+		}
+	}
+	if labeled != 9 || missing != 40 {
+		t.Errorf("Expected 9 labeled and 40 missing points but got %v and %v",
+			labeled, missing)
+	}
+
+	shift := 0.0
+	for _, anomaly := range s.Anomalies {
+		if anomaly.Kind == LevelShift {
+			shift = 30
+		}
+		i := anomaly.Start
+		if s.Missing[i] {
+			continue
+		}
+		expected := 100 + 0.5*float64(i) + shift
+		switch anomaly.Kind {
+		case Spike:
+			expected += 10
+		case Dip:
+			expected -= 10
+		}
+		if math.Abs(s.Values[i]-expected) > 1e-9 {
+			t.Errorf("Expected the %v at %v to be %v but got %v", anomaly.Kind, i,
+				expected, s.Values[i])
+		}
+	}
+
+	values, timestamps, labels := s.Present()
+	if len(values) != 160 || len(timestamps) != 160 || len(labels) != 160 {
+		t.Errorf("Expected 160 present points but got %v", len(values))
+	}
+}
+
+func TestMissingLeavesAnomaliesAlone(t *testing.T) {
+	s := Generate(20, VarianceChanges(2, 6, 3), Missing(0.9))
+	labeled, missing := 0, 0
+	for i := range s.Values {
+		if s.Labels[i] {
+			labeled++
+		}
+		if s.Missing[i] {
+			missing++
+		}
+	}
+	if labeled != 12 || missing != 8 {
+		t.Errorf("Expected 12 labeled points and the other 8 missing but got %v "+
+			"and %v", labeled, missing)
+	}
+}
+
+func TestNoiseDistributions(t *testing.T) {
+	for _, distribution := range []Distribution{Normal, Laplace, StudentT, Uniform} {
+		s := Generate(20000, Level(0), Noise(distribution, 2))
+		mean, variance := 0.0, 0.0
+		for _, v := range s.Values {
+			mean += v / float64(len(s.Values))
+		}
+		for _, v := range s.Values {
+			variance += (v - mean) * (v - mean) / float64(len(s.Values))
+		}
+		// The sample variance of Student's t with three degrees of freedom
+		// converges slowly, so allow for it.
+		if math.Abs(mean) > 0.1 || math.Abs(math.Sqrt(variance)-2) > 0.3 {
+			t.Errorf("Expected distribution %v to have a mean of 0 and a standard "+
+				"deviation of 2 but got %v and %v", distribution, mean,
+				math.Sqrt(variance))
+		}
+	}
+}
+
+func TestFindInjectedSpikes(t *testing.T) {
+	s := Generate(14*24, Seasonality(24, 10), Noise(Normal, 0.5), Spikes(3, 15),
+		Seed(3))
+	anomalies := rpca.FindAnomalies(s.Values, rpca.Frequency(24), rpca.AutoDiff(false))
+	for _, anomaly := range s.Anomalies {
+		if !anomalies.Positions[anomaly.Start] {
+			t.Errorf("Expected the spike at %v to be found", anomaly.Start)
+		}
+	}
+}
+
+func TestInvalidOptions(t *testing.T) {
+	options := map[string]func(*synthConfig) error{
+		"period":   Seasonality(0, 1),
+		"noise":    Noise(Normal, -1),
+		"missing":  Missing(1),
+		"count":    Spikes(-1, 10),
+		"length":   VarianceChanges(1, 0, 2),
+		"interval": Start(time.Time{}, 0),
+	}
+	for name, option := range options {
+		if err := option(&synthConfig{}); err == nil {
+			t.Errorf("Expected an error for the invalid %v", name)
+		}
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected Generate to panic on the invalid %v", name)
+				}
+			}()
+			Generate(10, option)
+		}()
+	}
+}